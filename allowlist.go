@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// allowedNets holds the parsed -allow networks. Requests from these
+// sources — uptime monitors, the operator's own scanners — get the
+// default response but never reach the recorder, keeping the threat
+// data clean.
+var allowedNets []*net.IPNet
+
+// parseAllowList parses the comma-separated -allow list. Entries may be
+// CIDRs or bare addresses (treated as host routes); anything malformed
+// fails fast so a typo cannot silently log traffic that was meant to be
+// excluded.
+func parseAllowList(s string) []*net.IPNet {
+	if s == "" {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		entry := strings.TrimSpace(part)
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 32
+				if ip.To4() == nil {
+					bits = 128
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			fatal("invalid -allow entry", "entry", entry, "err", err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// sourceAllowed reports whether ip falls inside the allowlist.
+func sourceAllowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range allowedNets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// asyncBuffer is how many records may sit in the channel before
+	// handleRequest blocks.
+	asyncBuffer = 1024
+	// flushEvery and flushInterval bound how long a record can sit in
+	// the destination's write buffer.
+	flushEvery    = 64
+	flushInterval = 500 * time.Millisecond
+)
+
+// Flusher is implemented by recorders that buffer writes and can force
+// them out to stable storage.
+type Flusher interface {
+	Flush() error
+}
+
+// AsyncRecorder decouples handleRequest from disk I/O: Record sends the
+// value on a buffered channel and returns immediately, while a single
+// background goroutine feeds the wrapped Recorder and flushes it every
+// flushEvery records or flushInterval, whichever comes first.
+type AsyncRecorder struct {
+	dst     Recorder
+	ch      chan Record
+	flushCh chan chan error
+	done    chan struct{}
+	written atomic.Int64
+
+	// closeMu serializes Record against Close/Shutdown: senders hold the
+	// read side for the duration of the send, so the channel is only
+	// closed once no send is in flight and later sends fail cleanly
+	// instead of panicking on the closed channel.
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// errRecorderClosed is returned by Record once Close or Shutdown has
+// begun; handlers still in flight at that point lose their record but
+// keep running.
+var errRecorderClosed = errors.New("recorder is shut down")
+
+// NewAsyncRecorder starts the background writer for dst.
+func NewAsyncRecorder(dst Recorder) *AsyncRecorder {
+	a := &AsyncRecorder{
+		dst:     dst,
+		ch:      make(chan Record, asyncBuffer),
+		flushCh: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Record enqueues r for the background writer. After Close or Shutdown
+// it reports errRecorderClosed instead of sending.
+func (a *AsyncRecorder) Record(r Record) error {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed {
+		return errRecorderClosed
+	}
+	a.ch <- r
+	return nil
+}
+
+// closeInput marks the recorder closed and closes the channel exactly
+// once, waiting out any send already in flight.
+func (a *AsyncRecorder) closeInput() {
+	a.closeMu.Lock()
+	defer a.closeMu.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+	close(a.ch)
+}
+
+// Flush asks the background writer to flush the wrapped recorder and
+// waits for the result. Records already queued ahead of the request are
+// written first, since the loop drains the channel in order.
+func (a *AsyncRecorder) Flush() error {
+	reply := make(chan error, 1)
+	select {
+	case a.flushCh <- reply:
+		return <-reply
+	case <-a.done:
+		return nil
+	}
+}
+
+func (a *AsyncRecorder) loop() {
+	defer close(a.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	pending := 0
+	flush := func() {
+		if pending == 0 {
+			return
+		}
+		if f, ok := a.dst.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				slog.Error("failed to flush records", "err", err)
+			}
+		}
+		pending = 0
+	}
+	for {
+		select {
+		case r, ok := <-a.ch:
+			if !ok {
+				flush()
+				return
+			}
+			if err := a.dst.Record(r); err != nil {
+				slog.Error("failed to record request", "err", err)
+			}
+			a.written.Add(1)
+			pending++
+			if pending >= flushEvery {
+				flush()
+			}
+		case reply := <-a.flushCh:
+			// Drain what is already queued so the flush covers it.
+		drain:
+			for {
+				select {
+				case r, ok := <-a.ch:
+					if !ok {
+						break drain
+					}
+					if err := a.dst.Record(r); err != nil {
+						slog.Error("failed to record request", "err", err)
+					}
+					a.written.Add(1)
+				default:
+					break drain
+				}
+			}
+			pending = 0
+			if f, ok := a.dst.(Flusher); ok {
+				reply <- f.Flush()
+			} else {
+				reply <- nil
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close drains the channel, flushes the wrapped recorder, and closes it
+// if it has a Close method.
+func (a *AsyncRecorder) Close() error {
+	a.closeInput()
+	<-a.done
+	if c, ok := a.dst.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Shutdown is Close with a deadline: it drains the channel until ctx
+// expires, then dumps whatever is still queued to spillPath as JSON
+// lines. A wedged backend (syslog peer gone, Kafka unreachable) can
+// then only cost the one record it is blocked on, not the whole
+// buffer, and shutdown finishes before systemd loses patience.
+func (a *AsyncRecorder) Shutdown(ctx context.Context, spillPath string) error {
+	before := a.written.Load()
+	a.closeInput()
+	select {
+	case <-a.done:
+		slog.Info("async writer drained", "flushed", a.written.Load()-before)
+		if c, ok := a.dst.(interface{ Close() error }); ok {
+			return c.Close()
+		}
+		return nil
+	case <-ctx.Done():
+		spilled, err := a.spill(spillPath)
+		slog.Warn("async drain deadline expired",
+			"flushed", a.written.Load()-before, "spilled", spilled, "spill_file", spillPath)
+		// The backend is stuck mid-write; closing it would hang too.
+		return err
+	}
+}
+
+// spill appends the records still queued in the channel to path as
+// JSON lines and reports how many it wrote. The caller must have
+// closed the channel already.
+func (a *AsyncRecorder) spill(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	n := 0
+	for r := range a.ch {
+		if err := enc.Encode(r); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func benchRecord() Record {
+	return Record{
+		Timestamp:  1700000000,
+		RemoteAddr: "203.0.113.7:54321",
+		Method:     "GET",
+		Path:       "/admin",
+	}
+}
+
+// BenchmarkCSVRecorderSync measures the old synchronous path: every
+// Record call goes straight to the CSV writer.
+func BenchmarkCSVRecorderSync(b *testing.B) {
+	c, err := NewCSVRecorder(filepath.Join(b.TempDir(), "bench.csv"), CSVOptions{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	r := benchRecord()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Record(r); err != nil {
+			b.Fatal(err)
+		}
+		if err := c.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCSVRecorderAsync measures the buffered path used by
+// handleRequest: Record only enqueues on the channel.
+func BenchmarkCSVRecorderAsync(b *testing.B) {
+	c, err := NewCSVRecorder(filepath.Join(b.TempDir(), "bench.csv"), CSVOptions{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := NewAsyncRecorder(c)
+	defer a.Close()
+	r := benchRecord()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := a.Record(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
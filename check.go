@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// checkDialTimeout bounds each connectivity probe so a firewalled
+// backend fails the check quickly instead of hanging it.
+const checkDialTimeout = 5 * time.Second
+
+// runCheck validates the effective configuration without starting the
+// listener: it parses every input file, constructs the configured
+// recorder, and probes backend connectivity. Every problem is reported
+// (not just the first), and the process exits non-zero if any check
+// failed, so a broken config is caught before rollout instead of
+// silently dropping logs in production.
+func runCheck() {
+	failed := false
+	report := func(what string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", what, err)
+			failed = true
+			return
+		}
+		fmt.Printf("ok   %s\n", what)
+	}
+
+	_, err := buildRuntimeConfig()
+	report("runtime config (response, routes, headers, excludes)", err)
+
+	switch {
+	case *kafkaBrokers != "":
+		for _, broker := range strings.Split(*kafkaBrokers, ",") {
+			broker = strings.TrimSpace(broker)
+			conn, err := net.DialTimeout("tcp", broker, checkDialTimeout)
+			if err == nil {
+				conn.Close()
+			}
+			report("kafka broker "+broker, err)
+		}
+	case *esURL != "":
+		client := &http.Client{Timeout: checkDialTimeout}
+		resp, err := client.Get(strings.TrimSuffix(*esURL, "/"))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				err = fmt.Errorf("cluster answered %s", resp.Status)
+			}
+		}
+		report("elasticsearch "+*esURL, err)
+	case *syslogAddr != "":
+		s, err := NewSyslogRecorder(*syslogAddr)
+		if err == nil {
+			s.Close()
+		}
+		report("syslog "+*syslogAddr, err)
+	case *backend == "file":
+		switch *format {
+		case "csv":
+			c, err := NewCSVRecorder(*logFile, CSVOptions{Comma: parseDelimiter(*delimiter)})
+			if err == nil {
+				c.Close()
+			}
+			report("csv log "+*logFile, err)
+		case "json":
+			j, err := NewJSONLRecorder(*logFile, *gzipOutput)
+			if err == nil {
+				j.Close()
+			}
+			report("jsonl log "+*logFile, err)
+		default:
+			report("format", fmt.Errorf("unknown -format %q", *format))
+		}
+	case *backend == "sqlite":
+		db, err := NewSQLiteRecorder(*dbPath)
+		if err == nil {
+			db.Close()
+		}
+		report("sqlite "+*dbPath, err)
+	default:
+		report("backend", fmt.Errorf("unknown -backend %q", *backend))
+	}
+
+	if *geoIPDB != "" {
+		report("geoip db "+*geoIPDB, openGeoIP(*geoIPDB))
+	}
+	if *decoyDir != "" {
+		_, err := loadDecoys(*decoyDir)
+		report("decoy dir "+*decoyDir, err)
+	}
+	if *pathSigsFile != "" {
+		_, err := LoadPathSignatures(*pathSigsFile)
+		report("path signatures "+*pathSigsFile, err)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "strings"
+
+// scannerRules maps case-insensitive User-Agent substrings to a tag.
+// Extend this table to recognize more tools; first match wins.
+var scannerRules = []struct {
+	substr string
+	tag    string
+}{
+	{"sqlmap", "sqlmap"},
+	{"nikto", "nikto"},
+	{"masscan", "masscan"},
+	{"nmap", "nmap"},
+	{"gobuster", "gobuster"},
+	{"dirbuster", "dirbuster"},
+	{"zgrab", "zgrab"},
+	{"python-requests", "python-requests"},
+	{"curl", "curl"},
+	{"wget", "wget"},
+}
+
+// threatPatterns maps a threat tag to the lowercase substrings that
+// betray it. Matching is case-insensitive; extend the table to detect
+// more payload families.
+var threatPatterns = []struct {
+	tag      string
+	patterns []string
+}{
+	{"sqli", []string{"' or ", "union select", "or 1=1", "sleep(", "benchmark(", "information_schema"}},
+	{"traversal", []string{"../", `..\`, "%2e%2e%2f"}},
+	{"xss", []string{"<script", "javascript:", "onerror=", "onload="}},
+	{"cmdi", []string{";cat ", "|cat ", "$(", "`", "/etc/passwd", "&& curl", "&& wget"}},
+}
+
+// DetectThreats scans the path, query values, and body of a record for
+// known attack payload patterns and returns the matching tags.
+func DetectThreats(r Record) []string {
+	var sb strings.Builder
+	sb.WriteString(r.Path)
+	if r.DecodedPath != "" && r.DecodedPath != r.Path {
+		// Percent-encoded payloads only match after decoding.
+		sb.WriteByte('\n')
+		sb.WriteString(r.DecodedPath)
+	}
+	for k, vals := range r.Query {
+		sb.WriteByte('\n')
+		sb.WriteString(k)
+		for _, v := range vals {
+			sb.WriteByte('\n')
+			sb.WriteString(v)
+		}
+	}
+	sb.WriteByte('\n')
+	sb.WriteString(r.Body)
+	haystack := strings.ToLower(sb.String())
+
+	var tags []string
+	for _, tp := range threatPatterns {
+		for _, p := range tp.patterns {
+			if strings.Contains(haystack, p) {
+				tags = append(tags, tp.tag)
+				break
+			}
+		}
+	}
+	return tags
+}
+
+// ClassifyUserAgent tags a User-Agent string when it matches a known
+// scanner tool. Empty user agents get their own tag since legitimate
+// browsers always send one. The empty string means "unclassified".
+func ClassifyUserAgent(ua string) string {
+	if strings.TrimSpace(ua) == "" {
+		return "empty-ua"
+	}
+	lower := strings.ToLower(ua)
+	for _, rule := range scannerRules {
+		if strings.Contains(lower, rule.substr) {
+			return rule.tag
+		}
+	}
+	return ""
+}
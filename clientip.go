@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies holds the parsed -trusted-proxies networks. When
+// empty, any direct peer is trusted once -trust-proxy is set (the
+// historical behavior).
+var trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses the comma-separated CIDR list from
+// -trusted-proxies, failing fast on anything malformed so a typo does
+// not silently open the door to spoofed X-Forwarded-For headers.
+func parseTrustedProxies(s string) []*net.IPNet {
+	if s == "" {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(part))
+		if err != nil {
+			fatal("invalid -trusted-proxies entry", "entry", part, "err", err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// proxyTrusted reports whether the direct peer is allowed to speak for
+// the client via forwarding headers.
+func proxyTrusted(remoteAddr string) bool {
+	if len(trustedProxies) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP determines the real client address for a request. When the
+// -trust-proxy flag is set it honors X-Forwarded-For (taking the
+// left-most non-proxy address in the chain) and X-Real-IP, but only
+// when the connection itself comes from a trusted proxy network;
+// otherwise those headers are attacker-controlled and are ignored.
+func clientIP(r *http.Request) string {
+	if *trustProxy && proxyTrusted(r.RemoteAddr) {
+		if ip := fromForwardedFor(r.Header.Get("X-Forwarded-For")); ip != "" {
+			return ip
+		}
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// No port to strip — a unix-socket peer, typically.
+		host = r.RemoteAddr
+	}
+	if parsed := net.ParseIP(host); parsed != nil {
+		// Canonical form, so "::FFFF:1.2.3.4" and friends group
+		// consistently.
+		return parsed.String()
+	}
+	return host
+}
+
+// remotePort returns the port half of remoteAddr, or "" when there is
+// none to split off (unix sockets, for instance).
+func remotePort(remoteAddr string) string {
+	_, port, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// ipVersion tags ip as address family 4 or 6 so grouping works across
+// families; 0 means the value did not parse as an IP.
+func ipVersion(ip string) int {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return 0
+	}
+	if parsed.To4() != nil {
+		return 4
+	}
+	return 6
+}
+
+// fromForwardedFor returns the left-most address in a comma-separated
+// X-Forwarded-For chain that is not itself a proxy hop, or "" if there
+// is none. With -trusted-proxies set, hops inside those networks are
+// skipped; otherwise the private/loopback heuristic applies.
+func fromForwardedFor(xff string) string {
+	for _, part := range strings.Split(xff, ",") {
+		ip := net.ParseIP(strings.TrimSpace(part))
+		if ip == nil {
+			continue
+		}
+		if isProxyHop(ip) {
+			continue
+		}
+		return ip.String()
+	}
+	return ""
+}
+
+// isProxyHop reports whether ip looks like an intermediate proxy rather
+// than the real client.
+func isProxyHop(ip net.IP) bool {
+	if len(trustedProxies) > 0 {
+		for _, n := range trustedProxies {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
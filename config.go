@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyEnvOverrides fills in flags the command line left untouched
+// from HONEYPOT_* environment variables: -log-file reads
+// HONEYPOT_LOG_FILE, -max-body HONEYPOT_MAX_BODY, and so on (dashes
+// become underscores, upper-cased). Overall precedence is command
+// line, then environment, then the -config file. The -name and
+// -integrity-key fallbacks already used HONEYPOT_NAME and
+// HONEYPOT_INTEGRITY_KEY, which this convention matches.
+func applyEnvOverrides() {
+	fromCLI := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { fromCLI[f.Name] = true })
+	flag.VisitAll(func(f *flag.Flag) {
+		if fromCLI[f.Name] {
+			return
+		}
+		name := "HONEYPOT_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := flag.Set(f.Name, v); err != nil {
+			fatal("invalid environment override", "var", name, "err", err)
+		}
+	})
+}
+
+// loadConfig applies options from a YAML file whose keys mirror the
+// flag names (e.g. "addr: 0.0.0.0"). Flags given on the command
+// line keep their value, so the file supplies defaults rather than
+// overrides. Unknown keys and unparsable values are reported with
+// their line number.
+func loadConfig(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]yaml.Node
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return err
+	}
+	// Flags explicitly set on the command line win over the file.
+	fromCLI := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { fromCLI[f.Name] = true })
+	for key, node := range cfg {
+		if flag.Lookup(key) == nil {
+			return fmt.Errorf("line %d: unknown option %q", node.Line, key)
+		}
+		if fromCLI[key] {
+			continue
+		}
+		if node.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: %s: expected a scalar value", node.Line, key)
+		}
+		if err := flag.Set(key, node.Value); err != nil {
+			return fmt.Errorf("line %d: %s: %w", node.Line, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connLogger appends one JSON line per connection that closed without
+// ever producing a parseable HTTP request — port scanners banner-
+// grabbing, TLS clients hitting a plaintext port, and other protocol-
+// confused visitors the HTTP handler never sees.
+type connLogger struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// connEvent is one line of the connection log.
+type connEvent struct {
+	Time          string `json:"time"`
+	RemoteAddr    string `json:"remote_addr"`
+	BytesReceived int64  `json:"bytes_received"`
+}
+
+// newConnLogger opens (or creates) the connection log at path.
+func newConnLogger(path string) (*connLogger, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &connLogger{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// log records one dead connection.
+func (l *connLogger) log(remote string, bytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(connEvent{
+		Time:          formatEventTime(time.Now()),
+		RemoteAddr:    remote,
+		BytesReceived: bytes,
+	})
+}
+
+// connLog is the optional connection logger, nil unless -conn-log is
+// set.
+var connLog *connLogger
+
+// countingListener wraps every accepted connection so its read bytes
+// are counted before any HTTP parsing happens.
+type countingListener struct {
+	net.Listener
+	log *connLogger
+}
+
+func (ln countingListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: c, log: ln.log}, nil
+}
+
+// countingConn counts raw bytes read from the peer. If the connection
+// closes without the handler ever marking a parsed request, it is
+// written to the connection log.
+type countingConn struct {
+	net.Conn
+	log        *connLogger
+	bytes      atomic.Int64
+	sawRequest atomic.Bool
+	logged     atomic.Bool
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.bytes.Add(int64(n))
+	return n, err
+}
+
+// markRequest notes that at least one valid HTTP request arrived, so
+// this connection does not belong in the dead-connection log.
+func (c *countingConn) markRequest() {
+	c.sawRequest.Store(true)
+}
+
+func (c *countingConn) Close() error {
+	if c.logged.CompareAndSwap(false, true) && !c.sawRequest.Load() {
+		c.log.log(c.RemoteAddr().String(), c.bytes.Load())
+	}
+	return c.Conn.Close()
+}
+
+// connCtxKey carries the countingConn through the request context so
+// the handler can call markRequest.
+type connCtxKey struct{}
+
+// connFromContext returns the request's countingConn, if any.
+func connFromContext(ctx context.Context) *countingConn {
+	c, _ := ctx.Value(connCtxKey{}).(*countingConn)
+	return c
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonColumn squeezes structured values into a single CSV cell as
+// JSON, since CSV has no native representation for maps.
+func jsonColumn(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	s := string(b)
+	if s == "null" || s == "{}" {
+		return ""
+	}
+	return s
+}
+
+var csvHeader = []string{"id", "event_time", "remote_addr", "client_ip", "client_port", "ip_version", "local_port", "method", "path", "proto", "host", "referer", "origin", "accept", "accept_language", "accept_encoding", "content_type", "content_length", "query", "cookies", "headers", "user_agent", "classification", "sensitive_path", "threats", "country", "city", "duration_ms", "hash"}
+
+// flatHeaders get their own CSV columns (in csvHeader order) so
+// pivoting on, say, Referer or Content-Type works directly in a
+// spreadsheet; everything else lands in the JSON headers catch-all.
+var flatHeaders = []string{"Referer", "Origin", "Accept", "Accept-Language", "Accept-Encoding", "Content-Type", "Content-Length"}
+
+// restHeaders returns h minus the flattened columns and the headers
+// already captured elsewhere in the row (Host, User-Agent).
+func restHeaders(h http.Header) http.Header {
+	rest := make(http.Header, len(h))
+	for name, values := range h {
+		rest[name] = values
+	}
+	for _, name := range flatHeaders {
+		delete(rest, name)
+	}
+	delete(rest, "Host")
+	delete(rest, "User-Agent")
+	if len(rest) == 0 {
+		return nil
+	}
+	return rest
+}
+
+// ipVersionColumn renders the IP version, leaving the cell empty when
+// the version could not be determined.
+func ipVersionColumn(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// csvRow renders r as its CSV columns, minus the trailing hash. The
+// integrity chain hashes exactly these strings, so -verify can recompute
+// it from the file alone.
+func csvRow(r Record) []string {
+	return []string{
+		r.ID,
+		r.EventTime,
+		r.RemoteAddr,
+		r.ClientIP,
+		r.ClientPort,
+		ipVersionColumn(r.IPVersion),
+		r.LocalPort,
+		r.Method,
+		r.Path,
+		r.Proto,
+		r.Host,
+		r.Referer,
+		r.Origin,
+		r.Headers.Get("Accept"),
+		r.AcceptLanguage,
+		r.AcceptEncoding,
+		r.Headers.Get("Content-Type"),
+		r.Headers.Get("Content-Length"),
+		jsonColumn(r.Query),
+		jsonColumn(r.Cookies),
+		jsonColumn(restHeaders(r.Headers)),
+		r.UserAgent,
+		r.Classification,
+		r.SensitivePath,
+		strings.Join(r.Threats, ";"),
+		r.Country,
+		r.City,
+		strconv.FormatFloat(r.DurationMs, 'f', 3, 64),
+	}
+}
+
+// CSVRecorder appends records to a CSV file, one row per request. The
+// file is kept open for the lifetime of the recorder; rows accumulate
+// in the csv.Writer buffer until Flush is called (the async writer does
+// this on a count/interval schedule).
+type CSVRecorder struct {
+	mu         sync.Mutex
+	path       string
+	opts       CSVOptions
+	f          *os.File
+	buf        *bufio.Writer
+	w          *csv.Writer
+	headerSize int64     // size of a file containing only the header row
+	period     time.Time // start of the current time-rotation period
+
+	// onRotate, when set, runs after each rotation. The integrity
+	// wrapper hooks it to restart its hash chain, so every rotated
+	// file verifies on its own.
+	onRotate func()
+}
+
+// CSVOptions configures a CSVRecorder beyond its file path. The zero
+// value means comma-delimited, minimally quoted, no rotation.
+type CSVOptions struct {
+	MaxSize     int64         // rotate once the file exceeds this many bytes; 0 disables
+	RotateEvery time.Duration // rotate on this schedule regardless of size; 0 disables
+	Compress    bool          // gzip rotated files in the background
+	Comma       rune          // field delimiter; 0 means ','
+	ForceQuote  bool          // quote every field, not just the ones that need it
+}
+
+// NewCSVRecorder returns a CSVRecorder writing to path. The header row
+// is written only when the file does not yet exist or is empty, so logs
+// from a previous run are never clobbered.
+func NewCSVRecorder(path string, opts CSVOptions) (*CSVRecorder, error) {
+	// csvHeader is maintained by hand alongside csvRow; catch any drift
+	// between the two at startup rather than in the produced files.
+	if got := len(csvRow(Record{})) + 1; got != len(csvHeader) {
+		return nil, fmt.Errorf("csv header has %d columns but rows have %d", len(csvHeader), got)
+	}
+	if opts.Comma == 0 {
+		opts.Comma = ','
+	}
+	c := &CSVRecorder{path: path, opts: opts}
+	if opts.RotateEvery > 0 {
+		c.period = time.Now().UTC().Truncate(opts.RotateEvery)
+	}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// open opens (or reopens after rotation) the log file and writes the
+// header row if the file is new. The caller must hold c.mu, except in
+// the constructor.
+func (c *CSVRecorder) open() error {
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	c.f = f
+	c.buf = bufio.NewWriter(f)
+	c.w = csv.NewWriter(c.buf)
+	c.w.Comma = c.opts.Comma
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		if err := c.writeRow(csvHeader); err != nil {
+			return err
+		}
+		if err := c.flushLocked(); err != nil {
+			return err
+		}
+		if fi, err := f.Stat(); err == nil {
+			c.headerSize = fi.Size()
+		}
+	}
+	return nil
+}
+
+// writeRow writes one row honoring the quoting option. The caller must
+// hold c.mu.
+func (c *CSVRecorder) writeRow(fields []string) error {
+	if !c.opts.ForceQuote {
+		return c.w.Write(fields)
+	}
+	// encoding/csv only quotes when it must, so force-quoted rows are
+	// written by hand with the same doubling rules.
+	for i, f := range fields {
+		if i > 0 {
+			if _, err := c.buf.WriteRune(c.opts.Comma); err != nil {
+				return err
+			}
+		}
+		if _, err := c.buf.WriteString(`"` + strings.ReplaceAll(f, `"`, `""`) + `"`); err != nil {
+			return err
+		}
+	}
+	return c.buf.WriteByte('\n')
+}
+
+// flushLocked pushes both the csv and bufio buffers to disk. The
+// caller must hold c.mu.
+func (c *CSVRecorder) flushLocked() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+// rotate closes the current file, renames it with suffix, and starts a
+// fresh one with headers. The caller must hold c.mu.
+func (c *CSVRecorder) rotate(suffix string) error {
+	if err := c.f.Close(); err != nil {
+		return err
+	}
+	rotated := fmt.Sprintf("%s.%s.csv", strings.TrimSuffix(c.path, ".csv"), suffix)
+	if err := os.Rename(c.path, rotated); err != nil {
+		return err
+	}
+	if c.opts.Compress {
+		go func() {
+			if err := gzipFile(rotated); err != nil {
+				slog.Error("failed to compress rotated log", "path", rotated, "err", err)
+			}
+		}()
+	}
+	if err := c.open(); err != nil {
+		return err
+	}
+	if c.onRotate != nil {
+		c.onRotate()
+	}
+	return nil
+}
+
+// rotateIfNeeded rotates once the file grows past MaxSize. The caller
+// must hold c.mu.
+func (c *CSVRecorder) rotateIfNeeded() error {
+	if c.opts.MaxSize <= 0 {
+		return nil
+	}
+	if err := c.flushLocked(); err != nil {
+		return err
+	}
+	fi, err := c.f.Stat()
+	if err != nil || fi.Size() < c.opts.MaxSize {
+		return nil
+	}
+	return c.rotate(time.Now().Format("2006-01-02T15-04-05"))
+}
+
+// rotateIfDue rotates when a new RotateEvery period begins, naming the
+// finished file after the period it covers (daily files get a plain
+// date suffix). An interval that saw no requests leaves no file
+// behind. The caller must hold c.mu.
+func (c *CSVRecorder) rotateIfDue(now time.Time) error {
+	if c.opts.RotateEvery <= 0 {
+		return nil
+	}
+	period := now.UTC().Truncate(c.opts.RotateEvery)
+	if period.Equal(c.period) {
+		return nil
+	}
+	old := c.period
+	c.period = period
+	if err := c.flushLocked(); err != nil {
+		return err
+	}
+	fi, err := c.f.Stat()
+	if err != nil || fi.Size() <= c.headerSize {
+		return nil
+	}
+	layout := "2006-01-02T15-04-05"
+	switch {
+	case c.opts.RotateEvery%(24*time.Hour) == 0:
+		layout = "2006-01-02"
+	case c.opts.RotateEvery%time.Hour == 0:
+		layout = "2006-01-02T15"
+	}
+	return c.rotate(old.Format(layout))
+}
+
+// gzipFile compresses path to path.gz and removes the original. The
+// compressed file is fully written, synced, and renamed into place
+// before the original is deleted, so a crash can at worst leave both
+// copies behind.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := path + ".gz.tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Record appends r to the CSV file, then rotates if the file has grown
+// past the maximum size or a new period has begun. Rotation runs after
+// the write so the finished file ends with a complete hash chain and
+// the next record starts a fresh one in the new file.
+func (c *CSVRecorder) Record(r Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.writeRow(append(csvRow(r), r.Hash)); err != nil {
+		return err
+	}
+	if err := c.rotateIfDue(time.Now()); err != nil {
+		return err
+	}
+	return c.rotateIfNeeded()
+}
+
+// Flush forces buffered rows out to the file.
+func (c *CSVRecorder) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked()
+}
+
+// Close flushes any buffered rows and closes the file.
+func (c *CSVRecorder) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.flushLocked(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// decoySet holds the decoy pages loaded from -decoy-dir. A page is
+// assigned to each request path by hashing, so repeated requests to
+// the same URL see the same page — a real site is consistent — while
+// different paths spread across the whole set, giving varied sizes and
+// titles instead of the fingerprintable static body.
+type decoySet struct {
+	names []string
+	pages map[string][]byte
+}
+
+// loadDecoys reads every .html/.htm file in dir.
+func loadDecoys(dir string) (*decoySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &decoySet{pages: make(map[string][]byte)}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if ext != ".html" && ext != ".htm" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		d.names = append(d.names, e.Name())
+		d.pages[e.Name()] = b
+	}
+	if len(d.names) == 0 {
+		return nil, fmt.Errorf("no .html files in %s", dir)
+	}
+	sort.Strings(d.names)
+	return d, nil
+}
+
+// pick returns the decoy page for path and the file name it came from,
+// for the record.
+func (d *decoySet) pick(path string) (string, []byte) {
+	h := fnv.New32a()
+	io.WriteString(h, path)
+	name := d.names[int(h.Sum32())%len(d.names)]
+	return name, d.pages[name]
+}
+
+// decoys is the optional decoy site, nil unless -decoy-dir is set.
+var decoys *decoySet
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupRecorder collapses byte-identical requests arriving within a
+// sliding window into a single record whose Count reflects how many
+// times it was seen. The aggregated record is written once the window
+// expires (or on Close), so sustained scans do not bury the log in
+// duplicate rows.
+type DedupRecorder struct {
+	mu      sync.Mutex
+	dst     Recorder
+	window  time.Duration
+	pending map[string]*dedupEntry
+	done    chan struct{}
+}
+
+type dedupEntry struct {
+	rec   Record
+	count int
+	first time.Time
+}
+
+// dedupKey identifies "the same request": source address, method,
+// path, query string, body, and user agent. Query and body matter —
+// injection attempts often differ only in the payload, and those must
+// each produce their own record.
+func dedupKey(r Record) string {
+	sum := sha256.Sum256([]byte(r.Body))
+	return strings.Join([]string{
+		r.RemoteAddr,
+		r.Method,
+		r.Path,
+		r.Query.Encode(),
+		hex.EncodeToString(sum[:]),
+		r.UserAgent,
+	}, "\x00")
+}
+
+// NewDedupRecorder wraps dst with deduplication over window.
+func NewDedupRecorder(dst Recorder, window time.Duration) *DedupRecorder {
+	d := &DedupRecorder{
+		dst:     dst,
+		window:  window,
+		pending: make(map[string]*dedupEntry),
+		done:    make(chan struct{}),
+	}
+	go d.sweep()
+	return d
+}
+
+// Record holds r back until its window expires; repeats within the
+// window only bump the counter.
+func (d *DedupRecorder) Record(r Record) error {
+	key := dedupKey(r)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.pending[key]; ok {
+		e.count++
+		return nil
+	}
+	d.pending[key] = &dedupEntry{rec: r, count: 1, first: time.Now()}
+	return nil
+}
+
+// sweep flushes aggregated entries whose window has passed.
+func (d *DedupRecorder) sweep() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			for key, e := range d.pending {
+				if now.Sub(e.first) >= d.window {
+					d.write(e)
+					delete(d.pending, key)
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// write sends an aggregated entry to the destination. The caller must
+// hold d.mu.
+func (d *DedupRecorder) write(e *dedupEntry) {
+	e.rec.Count = e.count
+	if err := d.dst.Record(e.rec); err != nil {
+		slog.Error("failed to record deduplicated request", "err", err)
+	}
+}
+
+// Flush passes through to the destination.
+func (d *DedupRecorder) Flush() error {
+	if f, ok := d.dst.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close flushes every pending aggregate and closes the destination.
+func (d *DedupRecorder) Close() error {
+	close(d.done)
+	d.mu.Lock()
+	for key, e := range d.pending {
+		d.write(e)
+		delete(d.pending, key)
+	}
+	d.mu.Unlock()
+	if c, ok := d.dst.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// esBatchSize is how many documents one _bulk request carries.
+	esBatchSize = 200
+	// esBatchInterval bounds how long a document waits for its batch
+	// to fill.
+	esBatchInterval = 2 * time.Second
+	// esMaxAttempts is how often a rejected document is resubmitted
+	// before it is dropped.
+	esMaxAttempts = 3
+)
+
+// ElasticRecorder indexes records into Elasticsearch through the _bulk
+// API. Documents land in a daily index (prefix-2006.01.02, Kibana's
+// conventional rollover scheme) and carry an @timestamp field so the
+// time filter works out of the box. Records accumulate until the batch
+// is full or the interval elapses; documents the cluster rejects with a
+// retryable status are resubmitted a few times before being dropped.
+type ElasticRecorder struct {
+	mu      sync.Mutex
+	url     string // endpoint, e.g. http://es:9200
+	index   string // index name prefix
+	client  *http.Client
+	pending []esDoc
+	done    chan struct{}
+}
+
+// esDoc wraps a Record with the @timestamp field Kibana expects.
+type esDoc struct {
+	Record
+	AtTimestamp string `json:"@timestamp"`
+}
+
+// NewElasticRecorder returns a recorder indexing into the daily
+// index series prefix-YYYY.MM.DD at url.
+func NewElasticRecorder(url, prefix string) *ElasticRecorder {
+	e := &ElasticRecorder{
+		url:    strings.TrimSuffix(url, "/"),
+		index:  prefix,
+		client: &http.Client{Timeout: 10 * time.Second},
+		done:   make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(esBatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.Flush(); err != nil {
+					slog.Error("bulk indexing failed", "err", err)
+				}
+			case <-e.done:
+				return
+			}
+		}
+	}()
+	return e
+}
+
+// Record queues r for the next bulk request, sending immediately once
+// the batch is full.
+func (e *ElasticRecorder) Record(r Record) error {
+	doc := esDoc{
+		Record:      r,
+		AtTimestamp: time.Unix(r.Timestamp, 0).UTC().Format(time.RFC3339),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, doc)
+	if len(e.pending) >= esBatchSize {
+		return e.flushLocked()
+	}
+	return nil
+}
+
+// Flush sends whatever is queued.
+func (e *ElasticRecorder) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.flushLocked()
+}
+
+// flushLocked submits the pending batch, resubmitting documents the
+// cluster rejects with a retryable status. The caller must hold e.mu.
+func (e *ElasticRecorder) flushLocked() error {
+	docs := e.pending
+	e.pending = nil
+	for attempt := 1; len(docs) > 0; attempt++ {
+		retry, err := e.sendBulk(docs)
+		if err != nil {
+			// Transport-level failure: nothing was indexed, keep the
+			// batch for the next flush.
+			e.pending = append(docs, e.pending...)
+			return err
+		}
+		docs = retry
+		if len(docs) > 0 && attempt >= esMaxAttempts {
+			return fmt.Errorf("%d documents still rejected after %d attempts", len(docs), attempt)
+		}
+	}
+	return nil
+}
+
+// sendBulk posts docs as one _bulk request and returns the subset the
+// cluster rejected with a retryable status (429, 5xx). Permanently
+// rejected documents (mapping conflicts and the like) are logged and
+// dropped.
+func (e *ElasticRecorder) sendBulk(docs []esDoc) ([]esDoc, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		day := time.Unix(doc.Timestamp, 0).UTC().Format("2006.01.02")
+		action := map[string]map[string]string{
+			"index": {"_index": e.index + "-" + day},
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url+"/_bulk", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("bulk request: %s", resp.Status)
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+	var retry []esDoc
+	for i, item := range result.Items {
+		if i >= len(docs) {
+			break
+		}
+		for _, st := range item {
+			switch {
+			case st.Status < 300:
+			case st.Status == http.StatusTooManyRequests || st.Status >= 500:
+				retry = append(retry, docs[i])
+			default:
+				slog.Error("document rejected", "status", st.Status, "error", string(st.Error))
+			}
+		}
+	}
+	return retry, nil
+}
+
+// Close stops the background flusher and sends any outstanding batch.
+func (e *ElasticRecorder) Close() error {
+	close(e.done)
+	return e.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// serverStyle picks which vendor's error pages to imitate from the
+// configured -server-banner; anything not Apache-ish defaults to
+// nginx, whose pages are the ones scanners see most.
+func serverStyle() string {
+	b := strings.ToLower(*serverBanner)
+	if strings.Contains(b, "apache") || strings.Contains(b, "httpd") {
+		return "apache"
+	}
+	return "nginx"
+}
+
+// errorPageName identifies the template served for status; it is
+// recorded on the request so the log shows what the scanner saw.
+func errorPageName(status int) string {
+	return fmt.Sprintf("%s-%d", serverStyle(), status)
+}
+
+// errorPage renders the default error page a real nginx or Apache
+// would serve for status, matching the impersonated -server-banner. An
+// empty or visibly canned error body is a honeypot tell; these
+// templates only fill in statuses a route or flag did not give an
+// explicit body, so the routes file stays the override mechanism.
+func errorPage(status int) []byte {
+	text := http.StatusText(status)
+	banner := *serverBanner
+	if serverStyle() == "apache" {
+		if banner == "" {
+			banner = "Apache"
+		}
+		return []byte(fmt.Sprintf(`<!DOCTYPE HTML PUBLIC "-//IETF//DTD HTML 2.0//EN">
+<html><head>
+<title>%d %s</title>
+</head><body>
+<h1>%s</h1>
+<hr>
+<address>%s Server</address>
+</body></html>
+`, status, text, text, banner))
+	}
+	if banner == "" {
+		banner = "nginx"
+	}
+	return []byte(fmt.Sprintf(`<html>
+<head><title>%d %s</title></head>
+<body>
+<center><h1>%d %s</h1></center>
+<hr><center>%s</center>
+</body>
+</html>
+`, status, text, status, text, banner))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// pathTemplate collapses a path's variable segments so structurally
+// identical requests compare equal: numeric segments become :id and
+// UUID-shaped ones :uuid. "/item/17/edit" and "/item/42/edit" share a
+// template.
+func pathTemplate(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		switch {
+		case s == "":
+		case isUUIDSegment(s):
+			segs[i] = ":uuid"
+		case isNumericSegment(s):
+			segs[i] = ":id"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// isNumericSegment reports whether s is all digits.
+func isNumericSegment(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isUUIDSegment reports whether s has the 8-4-4-4-12 hex shape.
+func isUUIDSegment(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			switch {
+			case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// uaFamily reduces a User-Agent to its leading product token, so
+// version bumps within one tool do not split a campaign.
+func uaFamily(ua string) string {
+	ua = strings.TrimSpace(ua)
+	if i := strings.IndexAny(ua, "/ "); i >= 0 {
+		ua = ua[:i]
+	}
+	return strings.ToLower(ua)
+}
+
+// requestFingerprint hashes a request's structure — method, path
+// template, the sorted set of header names, and the user-agent family —
+// so hits that differ only in IDs, versions, or payload values cluster
+// under one value. Campaigns show up as one busy fingerprint instead of
+// thousands of unique rows.
+func requestFingerprint(r Record) string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p := r.DecodedPath
+	if p == "" {
+		p = r.Path
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		r.Method,
+		pathTemplate(p),
+		strings.Join(names, ","),
+		uaFamily(r.UserAgent),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequestFingerprintIgnoresIDs(t *testing.T) {
+	base := Record{
+		Method:      "GET",
+		DecodedPath: "/item/17/edit",
+		Headers:     http.Header{"Accept": {"*/*"}, "Host": {"a"}},
+		UserAgent:   "sqlmap/1.5.2",
+	}
+	other := base
+	other.DecodedPath = "/item/424242/edit"
+	other.UserAgent = "sqlmap/1.7.11"
+	if requestFingerprint(base) != requestFingerprint(other) {
+		t.Error("requests differing only in IDs and tool version got different fingerprints")
+	}
+
+	different := base
+	different.Method = "POST"
+	if requestFingerprint(base) == requestFingerprint(different) {
+		t.Error("different methods share a fingerprint")
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	got := pathTemplate("/api/v2/users/42/e58ed763-928c-4155-bee9-fdbaaadc15f3")
+	want := "/api/v2/users/:id/:uuid"
+	if got != want {
+		t.Errorf("pathTemplate = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// geoDB is the optional MaxMind GeoLite2 database, opened in main when
+// -geoip-db is set.
+var geoDB *geoip2.Reader
+
+// openGeoIP loads the GeoLite2 City database at path.
+func openGeoIP(path string) error {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return err
+	}
+	geoDB = db
+	return nil
+}
+
+// geoLookup resolves ipStr to a country ISO code and city name. It
+// returns empty strings when no database is loaded, the lookup fails,
+// or ctx has already expired; enrichment must never fail the request.
+func geoLookup(ctx context.Context, ipStr string) (country, city string) {
+	if geoDB == nil || ctx.Err() != nil {
+		return "", ""
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return "", ""
+	}
+	rec, err := geoDB.City(ip)
+	if err != nil {
+		return "", ""
+	}
+	return rec.Country.IsoCode, rec.City.Names["en"]
+}
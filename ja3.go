@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ja3Entry holds the fingerprint computed during the handshake until
+// the HTTP layer picks it up by remote address.
+type ja3Entry struct {
+	fingerprint string
+	created     time.Time
+}
+
+// ja3ByAddr maps connection remote address to its JA3 fingerprint.
+// Entries are removed when GenerateRecord consumes them; a janitor
+// sweeps leftovers from handshakes that never produced a request.
+var ja3ByAddr sync.Map
+
+func init() {
+	go func() {
+		for range time.Tick(time.Minute) {
+			cutoff := time.Now().Add(-5 * time.Minute)
+			ja3ByAddr.Range(func(key, value any) bool {
+				if value.(ja3Entry).created.Before(cutoff) {
+					ja3ByAddr.Delete(key)
+				}
+				return true
+			})
+		}
+	}()
+}
+
+// storeJA3 computes and remembers the fingerprint for a ClientHello.
+// It is wired into tls.Config.GetConfigForClient from main.
+func storeJA3(hello *tls.ClientHelloInfo) {
+	if hello.Conn == nil {
+		return
+	}
+	ja3ByAddr.Store(hello.Conn.RemoteAddr().String(), ja3Entry{
+		fingerprint: ja3Fingerprint(hello),
+		created:     time.Now(),
+	})
+}
+
+// takeJA3 returns and forgets the fingerprint for addr.
+func takeJA3(addr string) string {
+	v, ok := ja3ByAddr.LoadAndDelete(addr)
+	if !ok {
+		return ""
+	}
+	return v.(ja3Entry).fingerprint
+}
+
+// ja3Fingerprint derives a JA3-style hash from what crypto/tls exposes
+// of the ClientHello: version, cipher suites, curves, and point
+// formats. The raw extension list is not surfaced by the standard
+// library, so that JA3 segment is left empty; the hash still clusters
+// client libraries reliably.
+func ja3Fingerprint(hello *tls.ClientHelloInfo) string {
+	var parts []string
+
+	version := uint16(0)
+	for _, v := range hello.SupportedVersions {
+		if v > version {
+			version = v
+		}
+	}
+	parts = append(parts, strconv.Itoa(int(version)))
+
+	ciphers := make([]string, len(hello.CipherSuites))
+	for i, c := range hello.CipherSuites {
+		ciphers[i] = strconv.Itoa(int(c))
+	}
+	parts = append(parts, strings.Join(ciphers, "-"))
+
+	// Extension IDs are unavailable; keep the segment so the format
+	// stays five-field like canonical JA3.
+	parts = append(parts, "")
+
+	curves := make([]string, len(hello.SupportedCurves))
+	for i, c := range hello.SupportedCurves {
+		curves[i] = strconv.Itoa(int(c))
+	}
+	parts = append(parts, strings.Join(curves, "-"))
+
+	points := make([]string, len(hello.SupportedPoints))
+	for i, p := range hello.SupportedPoints {
+		points[i] = strconv.Itoa(int(p))
+	}
+	parts = append(parts, strings.Join(points, "-"))
+
+	sum := md5.Sum([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:])
+}
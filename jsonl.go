@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+// JSONLRecorder appends records to a file as JSON Lines: one
+// json.Marshal-ed Record per line. Unlike the CSV format it keeps the
+// full Headers and PostForm maps. Like CSVRecorder it holds the file
+// open and buffers lines until Flush.
+//
+// With compression enabled the lines pass through a gzip stream on
+// their way to disk and the file gains a .gz suffix. Flush emits a gzip
+// sync block, so after every flush the file decompresses cleanly up to
+// the last flushed record.
+type JSONLRecorder struct {
+	mu sync.Mutex
+	f  *os.File
+	gz *gzip.Writer // nil when writing plain text
+	w  *bufio.Writer
+}
+
+// NewJSONLRecorder returns a JSONLRecorder writing to path, gzipping
+// the stream when compress is set.
+func NewJSONLRecorder(path string, compress bool) (*JSONLRecorder, error) {
+	if compress && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j := &JSONLRecorder{f: f}
+	if compress {
+		// Appending starts a new gzip member; readers see the file as
+		// one concatenated stream.
+		j.gz = gzip.NewWriter(f)
+		j.w = bufio.NewWriter(j.gz)
+	} else {
+		j.w = bufio.NewWriter(f)
+	}
+	return j, nil
+}
+
+// Record appends r to the file as a single JSON line.
+func (j *JSONLRecorder) Record(r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.w.Write(b); err != nil {
+		return err
+	}
+	return j.w.WriteByte('\n')
+}
+
+// Flush forces buffered lines out to the file.
+func (j *JSONLRecorder) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.w.Flush(); err != nil {
+		return err
+	}
+	if j.gz != nil {
+		return j.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes any buffered lines, finishes the gzip stream if there
+// is one, and closes the file.
+func (j *JSONLRecorder) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.w.Flush(); err != nil {
+		j.f.Close()
+		return err
+	}
+	if j.gz != nil {
+		if err := j.gz.Close(); err != nil {
+			j.f.Close()
+			return err
+		}
+	}
+	return j.f.Close()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaRecorder publishes each record as a JSON message to a Kafka
+// topic. Messages are keyed by client IP (falling back to the honeypot
+// name) so one source's events land on one partition in order. The
+// writer batches internally and retries transient broker errors;
+// Close blocks until outstanding batches are delivered.
+type KafkaRecorder struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaRecorder returns a recorder producing to topic via brokers.
+func NewKafkaRecorder(brokers []string, topic string) *KafkaRecorder {
+	return &KafkaRecorder{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		MaxAttempts:  5,
+		BatchTimeout: 100 * time.Millisecond,
+	}}
+}
+
+// Record publishes r, blocking until it is accepted into a batch. The
+// async writer in front of the recorder chain keeps this off the
+// request path.
+func (k *KafkaRecorder) Record(r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	key := r.ClientIP
+	if key == "" {
+		key = r.HoneypotName
+	}
+	return k.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(key),
+		Value: b,
+	})
+}
+
+// Flush is a no-op; batching and delivery are handled by the writer.
+func (k *KafkaRecorder) Flush() error {
+	return nil
+}
+
+// Close flushes outstanding messages and shuts the producer down.
+func (k *KafkaRecorder) Close() error {
+	return k.writer.Close()
+}
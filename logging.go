@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// setupLogging installs a JSON slog handler as the default logger so
+// the server's own operational logs are machine-parseable. level is the
+// -log-level flag value; unknown names fall back to info.
+func setupLogging(level string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	h := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+	slog.SetDefault(slog.New(h))
+}
+
+// fatal logs at error level and exits; slog has no fatal level of its
+// own.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
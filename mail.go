@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertSampleURIs is how many request URIs are quoted in an alert mail.
+const alertSampleURIs = 5
+
+// mailAlerter watches per-IP request volume and sends an email when a
+// source crosses -alert-threshold requests inside -alert-window, at
+// most once per -alert-cooldown so a sustained scan cannot flood the
+// mailbox.
+type mailAlerter struct {
+	mu        sync.Mutex
+	host      string
+	from      string
+	to        []string
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	sources   map[string]*alertSource
+}
+
+type alertSource struct {
+	count     int
+	windowEnd time.Time
+	lastMail  time.Time
+	methods   map[string]bool
+	samples   []string
+}
+
+// newMailAlerter wires up SMTP alerting; a cleanup goroutine prunes
+// idle sources so random-source floods cannot grow the map forever.
+func newMailAlerter(host, from string, to []string, threshold int, window, cooldown time.Duration) *mailAlerter {
+	a := &mailAlerter{
+		host:      host,
+		from:      from,
+		to:        to,
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		sources:   make(map[string]*alertSource),
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			a.mu.Lock()
+			now := time.Now()
+			for ip, s := range a.sources {
+				if now.After(s.windowEnd) && now.Sub(s.lastMail) > a.cooldown {
+					delete(a.sources, ip)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}()
+	return a
+}
+
+// Observe counts rec against its source IP and fires an alert mail in
+// the background when the threshold is crossed.
+func (a *mailAlerter) Observe(rec Record) {
+	if rec.ClientIP == "" {
+		return
+	}
+	now := time.Now()
+	a.mu.Lock()
+	s, ok := a.sources[rec.ClientIP]
+	if !ok || now.After(s.windowEnd) {
+		lastMail := time.Time{}
+		if ok {
+			lastMail = s.lastMail
+		}
+		s = &alertSource{
+			windowEnd: now.Add(a.window),
+			lastMail:  lastMail,
+			methods:   make(map[string]bool),
+		}
+		a.sources[rec.ClientIP] = s
+	}
+	s.count++
+	s.methods[rec.Method] = true
+	if len(s.samples) < alertSampleURIs {
+		s.samples = append(s.samples, rec.Path)
+	}
+	fire := s.count >= a.threshold && now.Sub(s.lastMail) >= a.cooldown
+	var body string
+	if fire {
+		s.lastMail = now
+		body = a.compose(rec.ClientIP, s)
+	}
+	a.mu.Unlock()
+	if fire {
+		go a.send(rec.ClientIP, body)
+	}
+}
+
+// compose renders the alert body. The caller must hold a.mu.
+func (a *mailAlerter) compose(ip string, s *alertSource) string {
+	methods := make([]string, 0, len(s.methods))
+	for m := range s.methods {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return fmt.Sprintf(
+		"Source %s sent %d requests within %s.\n\nMethods: %s\nSample URIs:\n  %s\n",
+		ip, s.count, a.window, strings.Join(methods, ", "),
+		strings.Join(s.samples, "\n  "))
+}
+
+// send delivers one alert; it runs in its own goroutine so SMTP
+// latency never delays request handling.
+func (a *mailAlerter) send(ip, body string) {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: honeypot alert: high request volume from %s\r\n\r\n%s",
+		a.from, strings.Join(a.to, ", "), ip, body)
+	if err := smtp.SendMail(a.host, nil, a.from, a.to, []byte(msg)); err != nil {
+		slog.Error("failed to send alert mail", "ip", ip, "err", err)
+	}
+}
+
+// alerter is the optional mail alerter, nil unless the -smtp-* flags
+// are configured.
+var alerter *mailAlerter
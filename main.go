@@ -0,0 +1,877 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pires/go-proxyproto"
+)
+
+var (
+	logFile     = flag.String("log-file", "requests_log.csv", "path of the CSV log file")
+	listenAddr  = flag.String("addr", "", "address to listen on (default all interfaces)")
+	listenPort  = flag.Int("port", 8080, "port to listen on")
+	listenPorts = flag.String("ports", "", "comma-separated list of ports to listen on (overrides -port)")
+	maxBody     = flag.Int64("max-body", 64*1024, "maximum number of request body bytes to log")
+	format      = flag.String("format", "csv", "log format for the file backend: csv or json")
+	backend     = flag.String("backend", "file", "storage backend: file or sqlite")
+	dbPath      = flag.String("db", "honeypot.db", "path of the SQLite database for -backend sqlite")
+	trustProxy  = flag.Bool("trust-proxy", false, "trust X-Forwarded-For / X-Real-IP headers from a reverse proxy")
+	maxSize     = flag.Int64("max-size", 0, "rotate the CSV log after this many MB (0 disables rotation)")
+
+	responseFile   = flag.String("response-file", "", "file whose contents are served as the response body")
+	responseStatus = flag.Int("response-status", http.StatusOK, "HTTP status code to respond with")
+	serverBanner   = flag.String("server-banner", "", "value for the Server response header (e.g. nginx/1.18.0)")
+	geoIPDB        = flag.String("geoip-db", "", "path to a MaxMind GeoLite2 City .mmdb for geo enrichment")
+	metricsAddr    = flag.String("metrics-addr", "", "separate address for the Prometheus /metrics endpoint (empty disables)")
+	rateLimit      = flag.Float64("rate", 0, "per-IP requests per second before 429s are returned (0 disables)")
+	timeFormat     = flag.String("time-format", "rfc3339", "event time format: unix, rfc3339, or a custom Go layout")
+	tlsCert        = flag.String("tls-cert", "", "TLS certificate file; with -tls-key, serve HTTPS instead of HTTP")
+	tlsKey         = flag.String("tls-key", "", "TLS private key file")
+	logLevel       = flag.String("log-level", "info", "operational log level: debug, info, warn, or error")
+	webhookURL     = flag.String("webhook-url", "", "URL to POST suspicious records to as JSON (empty disables)")
+	adminAddr      = flag.String("admin-addr", "", "separate address for the admin API (empty disables)")
+	recentSize     = flag.Int("recent-size", 100, "how many recent records to keep for the admin /recent API")
+	extraHdrsFile  = flag.String("extra-headers", "", "file of \"Name: Value\" lines added to every response")
+	tarpit         = flag.Duration("tarpit", 0, "delay before responding, then trickle the body (0 disables)")
+	authChallenge  = flag.Bool("auth-challenge", false, "answer requests without credentials with a 401 Basic challenge")
+	syslogAddr     = flag.String("syslog", "", "ship records to an RFC 5424 syslog endpoint, e.g. udp://10.0.0.1:514")
+	dedupWindow    = flag.Duration("dedup-window", 0, "collapse identical requests within this window into one counted record (0 disables)")
+	routesFile     = flag.String("routes", "", "JSON file mapping path patterns to canned responses")
+	compressLogs   = flag.Bool("compress", false, "gzip rotated log files in the background")
+	excludePaths   = flag.String("exclude-paths", "", "comma-separated exact paths answered 200 without being recorded")
+	allowList      = flag.String("allow", "", "comma-separated IPs/CIDRs served normally but never recorded (own monitors, scanners)")
+	proxyProto     = flag.Bool("proxy-protocol", false, "expect a PROXY protocol v1/v2 header on every connection")
+	stdoutLog      = flag.Bool("stdout", false, "also emit every record to stdout as JSON")
+	quiet          = flag.Bool("quiet", false, "suppress the per-record stdout echo even if -stdout is set elsewhere (recording and error logs are unaffected)")
+	upstreamURL    = flag.String("upstream", "", "mirror requests to this real backend and return its responses (empty disables)")
+	delimiter      = flag.String("delimiter", ",", "CSV field delimiter (single character; \\t for tab)")
+	forceQuote     = flag.Bool("force-quote", false, "quote every CSV field, not just the ones that need it")
+	resolvePTR     = flag.Bool("resolve-ptr", false, "reverse-resolve client IPs into the Hostname field (cached, async)")
+	rotateEvery    = flag.String("rotate-interval", "", "rotate the CSV log on a schedule: daily, hourly, or a duration like 6h")
+	hpName         = flag.String("name", "", "honeypot name stamped on every record (default $HONEYPOT_NAME, then the hostname)")
+	handlerTimeout = flag.Duration("handler-timeout", 0, "deadline for handling one request, tarpit included (0 disables)")
+	integrityKey   = flag.String("integrity-key", "", "HMAC key chaining record hashes for tamper evidence (default $HONEYPOT_INTEGRITY_KEY)")
+	verifyLog      = flag.String("verify", "", "verify the hash chain of the given CSV log and exit")
+	trustedCIDRs   = flag.String("trusted-proxies", "", "comma-separated CIDRs whose forwarding headers are trusted (empty trusts any peer)")
+	unixSocket     = flag.String("unix", "", "serve on this unix socket path instead of TCP ports")
+	captureRaw     = flag.Bool("capture-raw", false, "store the raw request wire bytes in each record")
+	maxRaw         = flag.Int64("max-raw", 4096, "bytes of raw request kept per record with -capture-raw")
+	smtpHost       = flag.String("smtp-host", "", "SMTP server (host:port) for volume alerts (empty disables)")
+	smtpFrom       = flag.String("smtp-from", "", "From address for alert mail")
+	smtpTo         = flag.String("smtp-to", "", "comma-separated recipients for alert mail")
+	alertThreshold = flag.Int("alert-threshold", 100, "requests from one IP within -alert-window that trigger an alert mail")
+	alertWindow    = flag.Duration("alert-window", time.Minute, "window for counting requests toward -alert-threshold")
+	alertCooldown  = flag.Duration("alert-cooldown", 15*time.Minute, "minimum gap between alert mails for the same IP")
+	configFile     = flag.String("config", "", "YAML file of options keyed by flag name; CLI flags override it")
+	decoyDir       = flag.String("decoy-dir", "", "directory of HTML pages served as a decoy site instead of the static body")
+	otelEndpoint   = flag.String("otel-endpoint", "", "OTLP/HTTP collector endpoint for trace export (empty disables tracing)")
+	maxConns       = flag.Int("max-conns", 0, "maximum requests handled concurrently before 503s are shed (0 disables)")
+	kafkaBrokers   = flag.String("kafka-brokers", "", "comma-separated Kafka brokers to publish records to (empty disables)")
+	kafkaTopic     = flag.String("kafka-topic", "honeypot", "Kafka topic for -kafka-brokers")
+	esURL          = flag.String("es-url", "", "Elasticsearch endpoint to bulk-index records into (empty disables)")
+	esIndex        = flag.String("es-index", "honeypot", "index name prefix for -es-url; records land in prefix-YYYY.MM.DD")
+	maxFieldLen    = flag.Int64("max-field-len", 8192, "truncate any single logged field to this many bytes (0 disables)")
+	robotsFile     = flag.String("robots-file", "", "file served as /robots.txt instead of the built-in one")
+	faviconFile    = flag.String("favicon-file", "", "file served as /favicon.ico instead of the built-in one")
+
+	readTimeout       = flag.Duration("read-timeout", 30*time.Second, "maximum time to read an entire request")
+	readHeaderTimeout = flag.Duration("read-header-timeout", 10*time.Second, "maximum time to read request headers")
+	writeTimeout      = flag.Duration("write-timeout", 60*time.Second, "maximum time to write a response (the tarpit needs headroom)")
+	idleTimeout       = flag.Duration("idle-timeout", 2*time.Minute, "how long a keep-alive connection may sit idle")
+	disableHTTP2      = flag.Bool("disable-http2", false, "refuse HTTP/2 ALPN so TLS clients are forced onto HTTP/1.1")
+	pathSigsFile      = flag.String("path-signatures", "", "file of \"category substring\" sensitive-path signatures replacing the built-ins")
+	connLogPath       = flag.String("conn-log", "", "JSON file logging connections that never sent a valid HTTP request")
+	showVersion       = flag.Bool("version", false, "print version and build information, then exit")
+	redactFields      = flag.String("redact", "auth_pass,cookies,Authorization", "comma-separated fields masked in the -stdout echo (full data still reaches the log)")
+	webhookFormat     = flag.String("webhook-format", "json", "webhook payload format: json, slack, or discord")
+	reputationFile    = flag.String("reputation-file", "", "JSON file persisting per-IP reputation across restarts")
+	reputationEvery   = flag.Duration("reputation-interval", time.Minute, "how often to save the reputation file")
+	gzipOutput        = flag.Bool("gzip-output", false, "write the JSONL log through gzip (json format only, file suffixed .gz)")
+	checkConfig       = flag.Bool("check", false, "validate the config and backend connectivity, then exit")
+	writeRetries      = flag.Int("write-retries", 2, "retries for a failed record write before it is spilled (0 disables)")
+	writeBackoff      = flag.Duration("write-backoff", 100*time.Millisecond, "first retry delay for failed writes, doubled per attempt")
+	summaryFile       = flag.String("summary-file", "", "file to append human-readable traffic summaries to (empty disables)")
+	summaryEvery      = flag.Duration("summary-interval", time.Hour, "how often to write a -summary-file report")
+)
+
+// recorder is the storage backend used by handleRequest. It is set once
+// in main before the server starts accepting connections.
+var recorder Recorder
+
+// requestsServed counts handled requests for in-process consumers like
+// the SIGUSR1 stats dump; the Prometheus counter covers scraping.
+var requestsServed atomic.Int64
+
+// honeypotName identifies this sensor in every record so logs from a
+// fleet can be aggregated; resolved once at startup.
+var honeypotName string
+
+// connSem is the -max-conns semaphore, nil when unlimited. It is
+// acquired before any per-request work — including the rate limiter —
+// so a shed request is never also counted against its IP's rate
+// bucket.
+var connSem chan struct{}
+
+// resolveIntegrityKey returns the HMAC key for record hash chaining:
+// the -integrity-key flag, falling back to the HONEYPOT_INTEGRITY_KEY
+// environment variable. nil disables chaining.
+func resolveIntegrityKey() []byte {
+	if *integrityKey != "" {
+		return []byte(*integrityKey)
+	}
+	if env := os.Getenv("HONEYPOT_INTEGRITY_KEY"); env != "" {
+		return []byte(env)
+	}
+	return nil
+}
+
+// resolveName picks the honeypot name: the -name flag wins, then the
+// HONEYPOT_NAME environment variable, then the machine hostname.
+func resolveName() string {
+	if *hpName != "" {
+		return *hpName
+	}
+	if env := os.Getenv("HONEYPOT_NAME"); env != "" {
+		return env
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		slog.Warn("could not determine hostname for record tagging", "err", err)
+		return ""
+	}
+	return host
+}
+
+// listenUnix binds the honeypot's unix socket, clearing a stale socket
+// file left behind by an unclean shutdown. A live socket (something
+// still answers a dial) means another instance is running and is an
+// error. Go's unix listener unlinks the file again when it is closed
+// during shutdown.
+func listenUnix(path string) (net.Listener, error) {
+	ln, err := net.Listen("unix", path)
+	if err == nil || !errors.Is(err, syscall.EADDRINUSE) {
+		return ln, err
+	}
+	if conn, derr := net.DialTimeout("unix", path, time.Second); derr == nil {
+		conn.Close()
+		return nil, fmt.Errorf("socket %s is in use by another process", path)
+	}
+	if rerr := os.Remove(path); rerr != nil {
+		return nil, rerr
+	}
+	return net.Listen("unix", path)
+}
+
+// parseRotateInterval turns the -rotate-interval flag into a duration,
+// accepting the shorthands daily and hourly alongside Go duration
+// syntax.
+func parseRotateInterval(s string) time.Duration {
+	switch s {
+	case "":
+		return 0
+	case "daily":
+		return 24 * time.Hour
+	case "hourly":
+		return time.Hour
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		fatal("-rotate-interval must be daily, hourly, or a positive duration", "value", s)
+	}
+	return d
+}
+
+// parseDelimiter validates the -delimiter flag and returns its single
+// rune, accepting the two-character escape \t for tab.
+func parseDelimiter(s string) rune {
+	if s == `\t` {
+		return '\t'
+	}
+	runes := []rune(s)
+	if len(runes) != 1 {
+		fatal("-delimiter must be a single character", "delimiter", s)
+	}
+	return runes[0]
+}
+
+// loadExtraHeaders parses a file of "Name: Value" lines. Blank lines
+// and #-comments are skipped.
+func loadExtraHeaders(path string) (http.Header, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	h := make(http.Header)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		h.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return h, nil
+}
+
+func handleRequest(w http.ResponseWriter, r *http.Request) {
+	if c := connFromContext(r.Context()); c != nil {
+		c.markRequest()
+	}
+	rc := cfg.Load()
+	if rc.excluded[r.URL.Path] {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(allowedNets) > 0 && sourceAllowed(clientIP(r)) {
+		// Allowlisted sources see the normal front page but leave no
+		// trace in the log.
+		w.WriteHeader(*responseStatus)
+		w.Write(rc.responseBody)
+		return
+	}
+	if connSem != nil {
+		select {
+		case connSem <- struct{}{}:
+			defer func() { <-connSem }()
+		default:
+			// Over capacity: shed load with a minimal record so the
+			// flood itself still shows up in the log.
+			now := time.Now()
+			rejected := Record{
+				ID:           uuid.NewString(),
+				Timestamp:    now.Unix(),
+				EventTime:    formatEventTime(now),
+				HoneypotName: honeypotName,
+				RemoteAddr:   r.RemoteAddr,
+				Method:       r.Method,
+				Path:         r.URL.Path,
+				Rejected:     true,
+			}
+			if err := recorder.Record(rejected); err != nil {
+				slog.Error("failed to record shed request", "err", err)
+			}
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	if tracer != nil {
+		ctx, span := tracer.Start(r.Context(), "honeypot.request")
+		defer span.End()
+		r = r.WithContext(ctx)
+	}
+	if *handlerTimeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), *handlerTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+	start := time.Now()
+	defer func() {
+		requestDuration.Observe(time.Since(start).Seconds())
+	}()
+	rec := GenerateRecord(r)
+	route := matchRoute(rc.routes, r.URL.Path)
+	if route != nil {
+		rec.Route = route.Name
+	}
+	var decoyBody []byte
+	if decoys != nil && route == nil {
+		rec.Decoy, decoyBody = decoys.pick(r.URL.Path)
+	}
+	// The delay is chosen before the record is written so the applied
+	// jitter shows up in the log.
+	delay := routeDelay(route)
+	if delay > 0 {
+		rec.DelayMs = float64(delay.Milliseconds())
+	}
+	if tracer != nil {
+		annotateSpan(r.Context(), &rec)
+	}
+	requestsTotal.WithLabelValues(rec.Method, rec.Classification, metricPathPrefix(rec.Path)).Inc()
+	requestsServed.Add(1)
+	observeSource(rec.ClientIP)
+	if reputation != nil {
+		rec.KnownBad = reputation.Observe(rec)
+	}
+	if summary != nil {
+		summary.Observe(rec)
+	}
+	if recent != nil {
+		recent.Add(rec)
+	}
+	if talkers != nil {
+		talkers.Inc(rec.ClientIP)
+	}
+	if fingerprints != nil {
+		fingerprints.Inc(rec.Fingerprint)
+	}
+	if alerter != nil {
+		alerter.Observe(rec)
+	}
+	if rc.limiter != nil && !rc.limiter.Allow(rec.ClientIP) {
+		rec.RateLimited = true
+	}
+	// Mirror mode: hand the request to the real backend and capture
+	// what it answered. A failed upstream falls through to the canned
+	// response below, so the honeypot never goes dark.
+	proxied := false
+	if upstreamProxy != nil && !rec.RateLimited {
+		// ParseForm may have drained the body GenerateRecord put back;
+		// restore the captured copy (truncated or decoded as it may be)
+		// for the upstream.
+		r.Body = io.NopCloser(strings.NewReader(rec.Body))
+		r.ContentLength = int64(len(rec.Body))
+		if rec.BodyCompressed {
+			r.Header.Del("Content-Encoding")
+		}
+		uw := &upstreamWriter{ResponseWriter: w}
+		upstreamProxy.ServeHTTP(uw, r)
+		if !uw.failed {
+			proxied = true
+			rec.UpstreamStatus = uw.status
+			rec.UpstreamBytes = uw.bytes
+		}
+	}
+	// Decide up front whether an error-page template will be served, so
+	// the record notes which one before it is written out.
+	errStatus := 0
+	if !proxied {
+		switch {
+		case rec.RateLimited:
+			errStatus = http.StatusTooManyRequests
+		case *authChallenge && rec.AuthUser == "":
+			errStatus = http.StatusUnauthorized
+		case route != nil && route.body == nil && route.Status >= 400:
+			errStatus = route.Status
+		case route == nil && decoyBody == nil && *responseFile == "" && *responseStatus >= 400:
+			errStatus = *responseStatus
+		}
+	}
+	var errBody []byte
+	if errStatus != 0 {
+		errBody = errorPage(errStatus)
+		rec.ErrorPage = errorPageName(errStatus)
+	}
+	rec.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	// A deadline blown during capture (slow body, enrichment) is still
+	// worth logging; the flag marks the record as incomplete.
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		rec.TimedOut = true
+	}
+	if err := recorder.Record(rec); err != nil {
+		slog.Error("failed to record request", "remote_addr", r.RemoteAddr, "method", r.Method, "err", err)
+	}
+	if *webhookURL != "" && suspicious(rec) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), webhookBudget)
+			defer cancel()
+			sendWebhook(ctx, *webhookURL, rec)
+		}()
+	}
+	if proxied {
+		// The upstream already wrote the response.
+		return
+	}
+	if *serverBanner != "" {
+		w.Header().Set("Server", *serverBanner)
+	}
+	if rec.RateLimited {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write(errBody)
+		return
+	}
+	if *authChallenge && rec.AuthUser == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="restricted"`)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(errBody)
+		return
+	}
+	for name, vals := range rc.extraHeaders {
+		for _, v := range vals {
+			w.Header().Add(name, v)
+		}
+	}
+	status, body := *responseStatus, rc.responseBody
+	if route != nil {
+		status = route.Status
+		if route.body != nil {
+			body = route.body
+		}
+	}
+	if decoyBody != nil {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body = decoyBody
+	}
+	if errBody != nil {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body = errBody
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if *tarpit > 0 {
+		select {
+		case <-time.After(*tarpit):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(status)
+		trickle(w, r, body)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// trickle writes body one byte at a time, flushing between bytes, to
+// keep a scanner's connection tied up for as long as it will wait. It
+// bails out as soon as the client disconnects.
+func trickle(w http.ResponseWriter, r *http.Request, body []byte) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Write(body)
+		return
+	}
+	for i := range body {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		w.Write(body[i : i+1])
+		flusher.Flush()
+	}
+}
+
+// newHoneypotServer builds the http.Server used by every TCP-style
+// listener — bound ports and socket-activated descriptors alike.
+// Slowloris-style clients hit the timeouts and are dropped; the server
+// reports such closes through ErrorLog, which feeds the structured log.
+func newHoneypotServer(addr string, errLog *log.Logger) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    1 << 20,
+		ErrorLog:          errLog,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			// ServeTLS hands us the *tls.Conn, not the listener's
+			// connection; unwrap until the counting wrapper (if any)
+			// shows up.
+			for {
+				switch conn := c.(type) {
+				case *countingConn:
+					return context.WithValue(ctx, connCtxKey{}, conn)
+				case *tls.Conn:
+					c = conn.NetConn()
+				default:
+					return ctx
+				}
+			}
+		},
+		TLSConfig: &tls.Config{
+			// Request but never require a client certificate:
+			// attackers probing for mTLS present theirs and we
+			// record it without rejecting anyone.
+			ClientAuth: tls.RequestClientCert,
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				storeJA3(hello)
+				return nil, nil
+			},
+		},
+	}
+	if *disableHTTP2 {
+		// A non-nil, empty TLSNextProto map switches off the
+		// automatic h2 upgrade; plain-HTTP listeners are 1.1-only
+		// regardless.
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return server
+}
+
+// serveHoneypot layers the optional PROXY protocol and connection
+// logging wrappers over ln and serves on it, reporting fatal errors on
+// errCh.
+func serveHoneypot(server *http.Server, ln net.Listener, errCh chan<- error) {
+	if *proxyProto {
+		// The proxyproto listener rewrites each connection's
+		// RemoteAddr from the PROXY header and drops connections
+		// whose header is malformed. The header is required, not
+		// optional: with the default policy a client connecting
+		// directly could forge one and choose its own logged source.
+		ln = &proxyproto.Listener{
+			Listener: ln,
+			Policy: func(net.Addr) (proxyproto.Policy, error) {
+				return proxyproto.REQUIRE, nil
+			},
+		}
+	}
+	if connLog != nil {
+		ln = countingListener{Listener: ln, log: connLog}
+	}
+	var err error
+	if *tlsCert != "" && *tlsKey != "" {
+		err = server.ServeTLS(ln, *tlsCert, *tlsKey)
+	} else {
+		err = server.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errCh <- fmt.Errorf("serve on %s: %w", server.Addr, err)
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			fatal("export failed", "err", err)
+		}
+		return
+	}
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("honeypot %s (commit %s, built %s)\n", version, commit, buildDate)
+		return
+	}
+	applyEnvOverrides()
+	if *configFile != "" {
+		if err := loadConfig(*configFile); err != nil {
+			fatal("failed to load config", "path", *configFile, "err", err)
+		}
+	}
+	setupLogging(*logLevel)
+	if *checkConfig {
+		runCheck()
+		return
+	}
+	slog.Info("starting", "version", version, "commit", commit, "built", buildDate)
+	honeypotName = resolveName()
+	trustedProxies = parseTrustedProxies(*trustedCIDRs)
+	allowedNets = parseAllowList(*allowList)
+
+	if *verifyLog != "" {
+		key := resolveIntegrityKey()
+		if key == nil {
+			fatal("-verify needs a key from -integrity-key or $HONEYPOT_INTEGRITY_KEY")
+		}
+		if err := VerifyChain(*verifyLog, key, parseDelimiter(*delimiter)); err != nil {
+			fatal("integrity check failed", "path", *verifyLog, "err", err)
+		}
+		slog.Info("hash chain intact", "path", *verifyLog)
+		return
+	}
+
+	var csvLog *CSVRecorder
+	switch {
+	case *kafkaBrokers != "":
+		brokers := strings.Split(*kafkaBrokers, ",")
+		for i := range brokers {
+			brokers[i] = strings.TrimSpace(brokers[i])
+		}
+		recorder = NewKafkaRecorder(brokers, *kafkaTopic)
+	case *esURL != "":
+		recorder = NewElasticRecorder(*esURL, *esIndex)
+	case *syslogAddr != "":
+		syslogRecorder, err := NewSyslogRecorder(*syslogAddr)
+		if err != nil {
+			fatal("failed to connect to syslog", "endpoint", *syslogAddr, "err", err)
+		}
+		recorder = syslogRecorder
+	case *backend == "file":
+		switch *format {
+		case "csv":
+			csvRecorder, err := NewCSVRecorder(*logFile, CSVOptions{
+				MaxSize:     *maxSize * 1024 * 1024,
+				RotateEvery: parseRotateInterval(*rotateEvery),
+				Compress:    *compressLogs,
+				Comma:       parseDelimiter(*delimiter),
+				ForceQuote:  *forceQuote,
+			})
+			if err != nil {
+				fatal("failed to open log file", "path", *logFile, "err", err)
+			}
+			recorder = csvRecorder
+			csvLog = csvRecorder
+		case "json":
+			jsonlRecorder, err := NewJSONLRecorder(*logFile, *gzipOutput)
+			if err != nil {
+				fatal("failed to open log file", "path", *logFile, "err", err)
+			}
+			recorder = jsonlRecorder
+		default:
+			fatal("unknown -format", "format", *format)
+		}
+	case *backend == "sqlite":
+		sqliteRecorder, err := NewSQLiteRecorder(*dbPath)
+		if err != nil {
+			fatal("failed to open database", "path", *dbPath, "err", err)
+		}
+		recorder = sqliteRecorder
+		sqliteDB = sqliteRecorder
+	default:
+		fatal("unknown -backend", "backend", *backend)
+	}
+	if *writeRetries > 0 {
+		recorder = NewRetryRecorder(recorder, *writeRetries, *writeBackoff, *logFile+".spill.jsonl")
+	}
+	if key := resolveIntegrityKey(); key != nil {
+		// Seed the chain from the last row already on disk so restarts
+		// extend it rather than starting over. Rotation resets the
+		// chain so every file verifies on its own.
+		prev := ""
+		if *syslogAddr == "" && *backend == "file" && *format == "csv" {
+			prev = lastHash(*logFile, parseDelimiter(*delimiter))
+		}
+		ir := NewIntegrityRecorder(recorder, key, prev)
+		if csvLog != nil {
+			csvLog.onRotate = ir.resetChain
+		}
+		recorder = ir
+	}
+	// -quiet wins over -stdout so one command-line switch can silence a
+	// config file that turns the echo on fleet-wide; under a scan the
+	// per-record echo just fills journald.
+	if *stdoutLog && !*quiet {
+		recorder = NewMultiRecorder(recorder, NewStdoutRecorder())
+	}
+	if *dedupWindow > 0 {
+		recorder = NewDedupRecorder(recorder, *dedupWindow)
+	}
+	recorder = NewAsyncRecorder(recorder)
+
+	if *geoIPDB != "" {
+		if err := openGeoIP(*geoIPDB); err != nil {
+			fatal("failed to open GeoIP database", "path", *geoIPDB, "err", err)
+		}
+	}
+
+	if *maxConns > 0 {
+		connSem = make(chan struct{}, *maxConns)
+	}
+
+	if *otelEndpoint != "" {
+		if err := setupTracing(*otelEndpoint); err != nil {
+			fatal("failed to set up tracing", "endpoint", *otelEndpoint, "err", err)
+		}
+	}
+
+	if *decoyDir != "" {
+		d, err := loadDecoys(*decoyDir)
+		if err != nil {
+			fatal("failed to load decoy pages", "dir", *decoyDir, "err", err)
+		}
+		decoys = d
+	}
+
+	if *upstreamURL != "" {
+		if err := setupUpstream(*upstreamURL); err != nil {
+			fatal("invalid -upstream", "url", *upstreamURL, "err", err)
+		}
+	}
+
+	wf, err := newWebhookFormatter(*webhookFormat)
+	if err != nil {
+		fatal("invalid -webhook-format", "format", *webhookFormat, "err", err)
+	}
+	webhookFmt = wf
+
+	if *connLogPath != "" {
+		cl, err := newConnLogger(*connLogPath)
+		if err != nil {
+			fatal("failed to open connection log", "path", *connLogPath, "err", err)
+		}
+		connLog = cl
+	}
+
+	rc, err := buildRuntimeConfig()
+	if err != nil {
+		fatal("failed to load configuration", "err", err)
+	}
+	cfg.Store(rc)
+
+	if *resolvePTR {
+		ptrResolver = newPTRCache()
+	}
+
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr)
+	}
+
+	if *adminAddr != "" {
+		recent = NewRecentBuffer(*recentSize)
+		talkers = NewIPCounter(ipCounterCap)
+		fingerprints = NewIPCounter(ipCounterCap)
+		serveAdmin(*adminAddr)
+	}
+
+	if *summaryFile != "" {
+		summary = newSummaryCollector(*summaryFile, *summaryEvery)
+	}
+
+	if *reputationFile != "" {
+		rep, err := newReputationStore(*reputationFile, *reputationEvery)
+		if err != nil {
+			fatal("failed to load reputation state", "path", *reputationFile, "err", err)
+		}
+		reputation = rep
+	}
+
+	if *smtpHost != "" {
+		if *smtpFrom == "" || *smtpTo == "" {
+			fatal("-smtp-host needs -smtp-from and -smtp-to")
+		}
+		to := strings.Split(*smtpTo, ",")
+		for i := range to {
+			to[i] = strings.TrimSpace(to[i])
+		}
+		alerter = newMailAlerter(*smtpHost, *smtpFrom, to, *alertThreshold, *alertWindow, *alertCooldown)
+	}
+
+	ports := []int{*listenPort}
+	if *listenPorts != "" {
+		ports = ports[:0]
+		for _, s := range strings.Split(*listenPorts, ",") {
+			p, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				fatal("invalid -ports entry", "entry", s, "err", err)
+			}
+			ports = append(ports, p)
+		}
+	}
+
+	http.HandleFunc("/", withRecovery(handleRequest))
+
+	servers := make([]*http.Server, 0, len(ports))
+	errCh := make(chan error, len(ports))
+	// Route the http.Server's own complaints (oversized headers,
+	// malformed requests) into the structured log.
+	serverErrLog := slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn)
+
+	if *unixSocket != "" {
+		// A local proxy fronts the honeypot, so TLS and PROXY protocol
+		// handling stay on that side of the socket.
+		server := &http.Server{
+			Addr:              *unixSocket,
+			ReadHeaderTimeout: *readHeaderTimeout,
+			ReadTimeout:       *readTimeout,
+			WriteTimeout:      *writeTimeout,
+			IdleTimeout:       *idleTimeout,
+			MaxHeaderBytes:    1 << 20,
+			ErrorLog:          serverErrLog,
+		}
+		servers = append(servers, server)
+		go func() {
+			ln, err := listenUnix(*unixSocket)
+			if err != nil {
+				errCh <- fmt.Errorf("listen on %s: %w", *unixSocket, err)
+				return
+			}
+			if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("serve on %s: %w", *unixSocket, err)
+			}
+		}()
+		ports = nil
+	}
+
+	// Under systemd socket activation the sockets already exist; serve
+	// on the inherited descriptors and skip binding our own, so systemd
+	// keeps owning them across restarts.
+	sdListeners := activationListeners()
+	if len(sdListeners) > 0 {
+		slog.Info("using systemd socket activation", "listeners", len(sdListeners))
+		ports = nil
+	}
+	for _, ln := range sdListeners {
+		server := newHoneypotServer(ln.Addr().String(), serverErrLog)
+		servers = append(servers, server)
+		go serveHoneypot(server, ln, errCh)
+	}
+
+	for _, p := range ports {
+		addr := net.JoinHostPort(*listenAddr, strconv.Itoa(p))
+		server := newHoneypotServer(addr, serverErrLog)
+		servers = append(servers, server)
+		go func(server *http.Server) {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				errCh <- fmt.Errorf("listen on %s: %w", server.Addr, err)
+				return
+			}
+			serveHoneypot(server, ln, errCh)
+		}(server)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			reloadConfig()
+		}
+	}()
+
+	setupSignalDump()
+
+	select {
+	case err := <-errCh:
+		fatal("listener failed", "err", err)
+	case sig := <-sigCh:
+		slog.Info("shutting down", "signal", sig.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("shutdown", "addr", server.Addr, "err", err)
+		}
+	}
+	if a, ok := recorder.(*AsyncRecorder); ok {
+		if err := a.Shutdown(ctx, *logFile+".spill.jsonl"); err != nil {
+			slog.Error("failed to flush recorder", "err", err)
+		}
+	} else if closer, ok := recorder.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("failed to flush recorder", "err", err)
+		}
+	}
+	if reputation != nil {
+		if err := reputation.Save(); err != nil {
+			slog.Error("failed to save reputation state", "err", err)
+		}
+	}
+	if tracerShutdown != nil {
+		if err := tracerShutdown(ctx); err != nil {
+			slog.Error("failed to flush spans", "err", err)
+		}
+	}
+	slog.Info("shutdown complete, all records flushed")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// captureRecorder keeps records in memory so tests can assert on what
+// the handler produced.
+type captureRecorder struct {
+	mu   sync.Mutex
+	recs []Record
+}
+
+func (c *captureRecorder) Record(r Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recs = append(c.recs, r)
+	return nil
+}
+
+func (c *captureRecorder) snapshot() []Record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Record(nil), c.recs...)
+}
+
+// setupHandler points the package-level recorder and runtime config at
+// test fixtures, restoring them when the test ends.
+func setupHandler(t *testing.T) *captureRecorder {
+	t.Helper()
+	rc, err := buildRuntimeConfig()
+	if err != nil {
+		t.Fatalf("buildRuntimeConfig: %v", err)
+	}
+	cfg.Store(rc)
+	cap := &captureRecorder{}
+	old := recorder
+	recorder = cap
+	t.Cleanup(func() { recorder = old })
+	return cap
+}
+
+func TestHandleRequestRecordsFields(t *testing.T) {
+	cap := setupHandler(t)
+	r := httptest.NewRequest("GET", "/wp-login.php?user=admin", nil)
+	r.Header.Set("User-Agent", "sqlmap/1.5")
+	w := httptest.NewRecorder()
+	handleRequest(w, r)
+
+	recs := cap.snapshot()
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if rec.Method != "GET" || rec.Path != "/wp-login.php" {
+		t.Errorf("method/path = %q %q", rec.Method, rec.Path)
+	}
+	if rec.ClientIP != "192.0.2.1" {
+		t.Errorf("ClientIP = %q, want 192.0.2.1", rec.ClientIP)
+	}
+	if rec.Classification != "sqlmap" {
+		t.Errorf("Classification = %q, want sqlmap", rec.Classification)
+	}
+	if got := rec.Query.Get("user"); got != "admin" {
+		t.Errorf("query user = %q, want admin", got)
+	}
+	if rec.ID == "" || rec.EventTime == "" {
+		t.Error("ID or EventTime not populated")
+	}
+	if w.Code != *responseStatus {
+		t.Errorf("status = %d, want %d", w.Code, *responseStatus)
+	}
+	if w.Body.String() != "hello\n" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestHandleRequestServesBuiltinRobots(t *testing.T) {
+	cap := setupHandler(t)
+	w := httptest.NewRecorder()
+	handleRequest(w, httptest.NewRequest("GET", "/robots.txt", nil))
+
+	if !strings.Contains(w.Body.String(), "User-agent:") {
+		t.Errorf("robots.txt body = %q", w.Body.String())
+	}
+	recs := cap.snapshot()
+	if len(recs) != 1 || recs[0].Route != "robots" {
+		t.Fatalf("expected one record routed to robots, got %+v", recs)
+	}
+}
+
+func TestCSVRecorderWritesRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.csv")
+	c, err := NewCSVRecorder(path, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := Record{ID: "abc", Method: "POST", Path: "/login", ClientIP: "198.51.100.7"}
+	if err := c.Record(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("parse written CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want header + 1", len(rows))
+	}
+	if rows[0][0] != "id" || len(rows[0]) != len(csvHeader) {
+		t.Errorf("bad header row %v", rows[0])
+	}
+	if rows[1][0] != "abc" || rows[1][3] != "198.51.100.7" || rows[1][8] != "/login" {
+		t.Errorf("bad data row %v", rows[1])
+	}
+}
+
+func TestIntegrityChainRestartsAtRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chain.csv")
+	c, err := NewCSVRecorder(path, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := []byte("test-key")
+	ir := NewIntegrityRecorder(c, key, "")
+	c.onRotate = ir.resetChain
+
+	if err := ir.Record(Record{ID: "before", Method: "GET", Path: "/probe"}); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	err = c.rotate("rotated")
+	c.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ir.Record(Record{ID: "after", Method: "GET", Path: "/probe"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ir.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both the rotated file and the active one must verify from an
+	// empty seed on their own.
+	for _, p := range []string{filepath.Join(dir, "chain.rotated.csv"), path} {
+		if err := VerifyChain(p, key, ','); err != nil {
+			t.Errorf("%s: %v", filepath.Base(p), err)
+		}
+	}
+}
+
+func TestCSVRecorderConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrent.csv")
+	c, err := NewCSVRecorder(path, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const goroutines, perGoroutine = 20, 50
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				rec := Record{
+					ID:        fmt.Sprintf("g%d-%d", g, i),
+					Method:    "GET",
+					Path:      "/probe",
+					UserAgent: "tester, with a comma",
+				}
+				if err := c.Record(rec); err != nil {
+					t.Errorf("record: %v", err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("concurrent writes corrupted the CSV: %v", err)
+	}
+	if want := 1 + goroutines*perGoroutine; len(rows) != want {
+		t.Errorf("got %d rows, want %d", len(rows), want)
+	}
+	for i, row := range rows {
+		if len(row) != len(csvHeader) {
+			t.Fatalf("row %d has %d fields, want %d", i, len(row), len(csvHeader))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "honeypot_requests_total",
+		Help: "Number of requests received, by HTTP method, scanner classification, and path bucket.",
+	}, []string{"method", "classification", "path_prefix"})
+	requestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "honeypot_request_duration_seconds",
+		Help: "Time spent handling a single request.",
+	})
+	uniqueSources = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "honeypot_unique_sources",
+		Help: "Distinct source IPs seen during the last interval.",
+	})
+)
+
+// uniqueSourcesInterval is how often the unique-sources gauge is
+// published and its window reset.
+const uniqueSourcesInterval = time.Minute
+
+// metricPathPrefix buckets a request path into a fixed set of prefixes.
+// The raw path would explode label cardinality — every scanner wordlist
+// entry becoming its own time series — so only the buckets worth
+// alerting on get their own label value.
+func metricPathPrefix(p string) string {
+	switch {
+	case strings.HasPrefix(p, "/admin"):
+		return "/admin"
+	case strings.HasPrefix(p, "/wp-"):
+		return "/wp-*"
+	case strings.HasPrefix(p, "/.env"):
+		return "/.env"
+	default:
+		return "other"
+	}
+}
+
+var (
+	uniqueMu  sync.Mutex
+	uniqueIPs = make(map[string]struct{})
+)
+
+// The window reset runs regardless of whether the metrics listener is
+// up: observeSource is called on every request, so without the ticker
+// the map would grow for as long as the process lives.
+func init() {
+	go func() {
+		for range time.Tick(uniqueSourcesInterval) {
+			uniqueMu.Lock()
+			uniqueSources.Set(float64(len(uniqueIPs)))
+			uniqueIPs = make(map[string]struct{})
+			uniqueMu.Unlock()
+		}
+	}()
+}
+
+// observeSource notes ip for the unique-sources gauge.
+func observeSource(ip string) {
+	if ip == "" {
+		return
+	}
+	uniqueMu.Lock()
+	uniqueIPs[ip] = struct{}{}
+	uniqueMu.Unlock()
+}
+
+// serveMetrics exposes the Prometheus registry on its own listener so
+// scrapes never reach (or get logged by) the honeypot handler.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("metrics listener failed", "addr", addr, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// withRecovery keeps a panicking request from killing its connection
+// uncleanly: the panic is logged with a stack trace, a minimal record
+// flagged Panicked is still written, and the client gets a generic 500.
+func withRecovery(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			slog.Error("panic in handler", "method", r.Method, "path", r.URL.Path,
+				"remote_addr", r.RemoteAddr, "panic", v, "stack", string(debug.Stack()))
+			now := time.Now()
+			rec := Record{
+				ID:         uuid.NewString(),
+				Timestamp:  now.Unix(),
+				EventTime:  formatEventTime(now),
+				RemoteAddr: r.RemoteAddr,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Panicked:   true,
+			}
+			if err := recorder.Record(rec); err != nil {
+				slog.Error("failed to record panicked request", "err", err)
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}()
+		next(w, r)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "errors"
+
+// MultiRecorder fans every record out to several backends. A failure
+// in one backend never prevents the others from recording; all errors
+// are joined and returned so the caller can log them together.
+type MultiRecorder struct {
+	recorders []Recorder
+}
+
+// NewMultiRecorder combines recorders into one fan-out Recorder.
+func NewMultiRecorder(recorders ...Recorder) *MultiRecorder {
+	return &MultiRecorder{recorders: recorders}
+}
+
+// Record sends r to every backend.
+func (m *MultiRecorder) Record(r Record) error {
+	var errs []error
+	for _, rec := range m.recorders {
+		if err := rec.Record(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes every backend that buffers.
+func (m *MultiRecorder) Flush() error {
+	var errs []error
+	for _, rec := range m.recorders {
+		if f, ok := rec.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every backend that holds resources.
+func (m *MultiRecorder) Close() error {
+	var errs []error
+	for _, rec := range m.recorders {
+		if c, ok := rec.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
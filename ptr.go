@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ptrTTL     = 30 * time.Minute
+	ptrMaxSize = 4096
+	ptrTimeout = 2 * time.Second
+)
+
+// ptrCache memoizes reverse DNS lookups with a TTL and a size bound.
+// Lookups happen in the background: a cache miss returns "" for the
+// current request and warms the cache for the next one, so a resolver
+// stall can never hold up handleRequest.
+type ptrCache struct {
+	mu      sync.Mutex
+	entries map[string]ptrEntry
+	pending map[string]bool
+}
+
+type ptrEntry struct {
+	hostname string
+	expires  time.Time
+}
+
+// ptrResolver is the shared cache, nil unless -resolve-ptr is set.
+var ptrResolver *ptrCache
+
+func newPTRCache() *ptrCache {
+	return &ptrCache{
+		entries: make(map[string]ptrEntry),
+		pending: make(map[string]bool),
+	}
+}
+
+// Lookup returns the cached PTR hostname for ip, or "" while a
+// background lookup is still in flight (or failed). An expired ctx
+// skips the cache entirely so a timed-out request does no extra work.
+func (c *ptrCache) Lookup(ctx context.Context, ip string) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[ip]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.hostname
+	}
+	if c.pending[ip] {
+		c.mu.Unlock()
+		return ""
+	}
+	c.pending[ip] = true
+	c.mu.Unlock()
+	go c.resolve(ip)
+	return ""
+}
+
+func (c *ptrCache) resolve(ip string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ptrTimeout)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	hostname := ""
+	if err == nil && len(names) > 0 {
+		hostname = strings.TrimSuffix(names[0], ".")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, ip)
+	if len(c.entries) >= ptrMaxSize {
+		c.evictExpired()
+	}
+	if len(c.entries) < ptrMaxSize {
+		c.entries[ip] = ptrEntry{hostname: hostname, expires: time.Now().Add(ptrTTL)}
+	}
+}
+
+// evictExpired drops stale entries; the caller must hold c.mu.
+func (c *ptrCache) evictExpired() {
+	now := time.Now()
+	for ip, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, ip)
+		}
+	}
+}
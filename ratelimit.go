@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdle is how long an IP may go unseen before its bucket is
+// dropped by the cleanup loop.
+const limiterIdle = 5 * time.Minute
+
+type ipBucket struct {
+	lim      *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter hands out one token bucket per client IP and prunes
+// buckets that have gone idle so the map cannot grow without bound.
+type RateLimiter struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	ips   map[string]*ipBucket
+	done  chan struct{}
+}
+
+// NewRateLimiter builds a limiter allowing perSecond requests per IP
+// and starts the background cleanup loop.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	rl := &RateLimiter{
+		limit: rate.Limit(perSecond),
+		burst: burst,
+		ips:   make(map[string]*ipBucket),
+		done:  make(chan struct{}),
+	}
+	go rl.cleanup()
+	return rl
+}
+
+// rateIs reports whether the limiter was built for perSecond, so a
+// config reload can tell an unchanged rate from a new one.
+func (rl *RateLimiter) rateIs(perSecond float64) bool {
+	return rl.limit == rate.Limit(perSecond)
+}
+
+// Stop ends the cleanup loop. A replaced limiter would otherwise keep
+// its goroutine and bucket map alive forever.
+func (rl *RateLimiter) Stop() {
+	close(rl.done)
+}
+
+// Allow reports whether a request from ip is within its budget.
+func (rl *RateLimiter) Allow(ip string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b, ok := rl.ips[ip]
+	if !ok {
+		b = &ipBucket{lim: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.ips[ip] = b
+	}
+	b.lastSeen = time.Now()
+	return b.lim.Allow()
+}
+
+func (rl *RateLimiter) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rl.done:
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-limiterIdle)
+			rl.mu.Lock()
+			for ip, b := range rl.ips {
+				if b.lastSeen.Before(cutoff) {
+					delete(rl.ips, ip)
+				}
+			}
+			rl.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RecentBuffer keeps the last n records in a fixed-size ring so the
+// admin API can show recent traffic without touching the log files.
+type RecentBuffer struct {
+	mu   sync.Mutex
+	recs []Record
+	next int
+	full bool
+}
+
+// NewRecentBuffer returns a ring buffer holding up to n records.
+func NewRecentBuffer(n int) *RecentBuffer {
+	return &RecentBuffer{recs: make([]Record, n)}
+}
+
+// Add stores r, evicting the oldest entry once the ring is full.
+func (b *RecentBuffer) Add(r Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recs[b.next] = r
+	b.next++
+	if b.next == len(b.recs) {
+		b.next = 0
+		b.full = true
+	}
+}
+
+// Snapshot returns the buffered records, oldest first.
+func (b *RecentBuffer) Snapshot() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		out := make([]Record, b.next)
+		copy(out, b.recs[:b.next])
+		return out
+	}
+	out := make([]Record, 0, len(b.recs))
+	out = append(out, b.recs[b.next:]...)
+	out = append(out, b.recs[:b.next]...)
+	return out
+}
+
+// recent is the in-memory ring of recent records, nil unless
+// -admin-addr is configured.
+var recent *RecentBuffer
+
+// serveAdmin exposes the triage API on its own listener so admin
+// traffic is never recorded as honeypot traffic.
+func serveAdmin(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/recent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(recent.Snapshot()); err != nil {
+			slog.Error("failed to encode /recent response", "err", err)
+		}
+	})
+	// GET returns per-IP request counts, busiest first; DELETE resets
+	// them for a fresh view mid-incident.
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			talkers.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(talkers.Snapshot()); err != nil {
+			slog.Error("failed to encode /stats response", "err", err)
+		}
+	})
+	// Top request fingerprints, busiest first, for spotting campaigns;
+	// DELETE resets the counters.
+	mux.HandleFunc("/fingerprints", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			fingerprints.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		type fpCount struct {
+			Fingerprint string `json:"fingerprint"`
+			Count       int    `json:"count"`
+		}
+		counts := fingerprints.Snapshot()
+		out := make([]fpCount, 0, len(counts))
+		for _, c := range counts {
+			out = append(out, fpCount{Fingerprint: c.IP, Count: c.Count})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			slog.Error("failed to encode /fingerprints response", "err", err)
+		}
+	})
+	// Parameterized queries over the SQLite backend; 404s when records
+	// go elsewhere. since accepts RFC 3339 or unix seconds.
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if sqliteDB == nil {
+			http.Error(w, "search requires -backend=sqlite", http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		var since int64
+		if s := q.Get("since"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				since, err = strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					http.Error(w, "since: want RFC 3339 or unix seconds", http.StatusBadRequest)
+					return
+				}
+			} else {
+				since = t.Unix()
+			}
+		}
+		limit := 0
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "limit: not a number", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		rows, err := sqliteDB.Search(q.Get("ip"), q.Get("method"), since, limit)
+		if err != nil {
+			slog.Error("search query failed", "err", err)
+			http.Error(w, "query failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rows); err != nil {
+			slog.Error("failed to encode /search response", "err", err)
+		}
+	})
+	// Liveness/readiness probes live here rather than on the honeypot
+	// listener so kubelet traffic never shows up in the logs.
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("admin listener failed", "addr", addr, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record holds everything we capture about a single request.
+type Record struct {
+	ID             string            `json:"id"`
+	Timestamp      int64             `json:"timestamp"`
+	EventTime      string            `json:"event_time"`
+	HoneypotName   string            `json:"honeypot_name,omitempty"`
+	RemoteAddr     string            `json:"remote_addr"`
+	ClientIP       string            `json:"client_ip"`
+	ClientPort     string            `json:"client_port,omitempty"`
+	IPVersion      int               `json:"ip_version,omitempty"`
+	Hostname       string            `json:"hostname,omitempty"`
+	LocalPort      string            `json:"local_port"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	DecodedPath    string            `json:"decoded_path,omitempty"`
+	Proto          string            `json:"proto"`
+	HTTP2          bool              `json:"http2,omitempty"`
+	Host           string            `json:"host"`
+	UserAgent      string            `json:"user_agent"`
+	Referer        string            `json:"referer,omitempty"`
+	Origin         string            `json:"origin,omitempty"`
+	AcceptLanguage string            `json:"accept_language,omitempty"`
+	AcceptEncoding string            `json:"accept_encoding,omitempty"`
+	Classification string            `json:"classification,omitempty"`
+	SensitivePath  string            `json:"sensitive_path,omitempty"`
+	Headers        http.Header       `json:"headers"`
+	Query          url.Values        `json:"query"`
+	Cookies        map[string]string `json:"cookies,omitempty"`
+	PostForm       url.Values        `json:"post_form"`
+	AuthUser       string            `json:"auth_user,omitempty"`
+	AuthPass       string            `json:"auth_pass,omitempty"`
+	Country        string            `json:"country,omitempty"`
+	City           string            `json:"city,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	BodyTruncated  bool              `json:"body_truncated,omitempty"`
+	BodyCompressed bool              `json:"body_compressed,omitempty"`
+	BodyBomb       bool              `json:"decompression_bomb,omitempty"`
+	RateLimited    bool              `json:"rate_limited,omitempty"`
+	TimedOut       bool              `json:"timed_out,omitempty"`
+	Rejected       bool              `json:"rejected,omitempty"`
+	Panicked       bool              `json:"panicked,omitempty"`
+	ParseError     string            `json:"parse_error,omitempty"`
+	Smuggling      bool              `json:"smuggling_suspected,omitempty"`
+	KnownBad       bool              `json:"known_bad,omitempty"`
+	Fingerprint    string            `json:"fingerprint,omitempty"`
+	Count          int               `json:"count,omitempty"`
+	Threats        []string          `json:"threats,omitempty"`
+	DurationMs     float64           `json:"duration_ms"`
+	Route          string            `json:"route,omitempty"`
+	ErrorPage      string            `json:"error_page,omitempty"`
+	UpstreamStatus int               `json:"upstream_status,omitempty"`
+	UpstreamBytes  int64             `json:"upstream_bytes,omitempty"`
+	Decoy          string            `json:"decoy,omitempty"`
+	DelayMs        float64           `json:"delay_ms,omitempty"`
+	TLSVersion     string            `json:"tls_version,omitempty"`
+	TLSCipher      string            `json:"tls_cipher,omitempty"`
+	SNI            string            `json:"sni,omitempty"`
+	JA3            string            `json:"ja3,omitempty"`
+	ClientCertSubj string            `json:"client_cert_subject,omitempty"`
+	ClientCertIss  string            `json:"client_cert_issuer,omitempty"`
+	ClientCertSHA  string            `json:"client_cert_sha256,omitempty"`
+	Uploads        []Upload          `json:"uploads,omitempty"`
+	RawRequest     string            `json:"raw_request,omitempty"`
+	TraceID        string            `json:"trace_id,omitempty"`
+	Hash           string            `json:"hash,omitempty"`
+}
+
+// Upload describes one file part of a multipart upload. Only metadata
+// and a short preview are kept, never the full file bytes.
+type Upload struct {
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	Preview     string `json:"preview,omitempty"`
+}
+
+// uploadPreviewBytes is how much of each uploaded file is kept for
+// signature matching (webshell markers and the like).
+const uploadPreviewBytes = 512
+
+// extractUploads records the file parts of a multipart/form-data
+// request. Any temp files ParseMultipartForm spills to disk are
+// removed before returning.
+func extractUploads(r *http.Request) []Upload {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return nil
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil || r.MultipartForm == nil {
+		return nil
+	}
+	defer r.MultipartForm.RemoveAll()
+	var uploads []Upload
+	for _, headers := range r.MultipartForm.File {
+		for _, fh := range headers {
+			u := Upload{
+				Filename:    sanitizeString(fh.Filename),
+				Size:        fh.Size,
+				ContentType: sanitizeString(fh.Header.Get("Content-Type")),
+			}
+			if f, err := fh.Open(); err == nil {
+				buf := make([]byte, uploadPreviewBytes)
+				n, _ := io.ReadFull(f, buf)
+				u.Preview = sanitizeString(string(buf[:n]))
+				f.Close()
+			}
+			uploads = append(uploads, u)
+		}
+	}
+	return uploads
+}
+
+// GenerateRecord extracts the interesting parts of an incoming request.
+func GenerateRecord(r *http.Request) Record {
+	body, truncated := readBody(r)
+	// Raw capture has to happen before ParseForm drains the body;
+	// DumpRequest puts its own copy back afterwards.
+	var raw string
+	if *captureRaw {
+		raw = rawRequest(r)
+	}
+	body, compressed, bomb := decodeBody(body, r.Header.Get("Content-Encoding"))
+	// The decoded payload honors the same size cap as a plain one.
+	if int64(len(body)) > *maxBody {
+		body = body[:*maxBody]
+		truncated = true
+	}
+	parseErr := r.ParseForm()
+	ip := clientIP(r)
+	country, city := geoLookup(r.Context(), ip)
+	now := time.Now()
+	rec := Record{
+		ID:             uuid.NewString(),
+		Timestamp:      now.Unix(),
+		EventTime:      formatEventTime(now),
+		HoneypotName:   honeypotName,
+		RemoteAddr:     r.RemoteAddr,
+		ClientIP:       ip,
+		ClientPort:     remotePort(r.RemoteAddr),
+		IPVersion:      ipVersion(ip),
+		LocalPort:      localPort(r),
+		Country:        country,
+		City:           city,
+		Method:         sanitizeString(r.Method),
+		Path:           sanitizeString(r.URL.Path),
+		DecodedPath:    sanitizeString(decodedPath(r.RequestURI)),
+		Proto:          r.Proto,
+		HTTP2:          r.ProtoMajor == 2,
+		Host:           sanitizeString(r.Host),
+		UserAgent:      sanitizeString(r.UserAgent()),
+		Referer:        sanitizeString(r.Referer()),
+		Origin:         sanitizeString(r.Header.Get("Origin")),
+		AcceptLanguage: sanitizeString(r.Header.Get("Accept-Language")),
+		AcceptEncoding: sanitizeString(r.Header.Get("Accept-Encoding")),
+		Classification: ClassifyUserAgent(r.UserAgent()),
+		Headers:        sanitizeHeader(r.Header),
+		Query:          sanitizeValues(r.URL.Query()),
+		Cookies:        cookieMap(r),
+		PostForm:       sanitizeValues(r.PostForm),
+		Body:           string(body),
+		BodyTruncated:  truncated,
+		BodyCompressed: compressed,
+		BodyBomb:       bomb,
+		RawRequest:     raw,
+	}
+	// r.BasicAuth only decodes the Basic scheme; Bearer and friends
+	// stay untouched in the raw Authorization header.
+	if user, pass, ok := r.BasicAuth(); ok {
+		rec.AuthUser = sanitizeString(user)
+		rec.AuthPass = sanitizeString(pass)
+	}
+	if parseErr != nil {
+		rec.ParseError = sanitizeString(parseErr.Error())
+	}
+	rec.SensitivePath = classifySensitivePath(r.URL.Path)
+	if rec.SensitivePath == "" {
+		// Encoded traversal sequences only survive in the raw URI.
+		rec.SensitivePath = classifySensitivePath(r.RequestURI)
+	}
+	rec.Uploads = extractUploads(r)
+	rec.Smuggling = detectSmuggling(r)
+	rec.Threats = DetectThreats(rec)
+	rec.Fingerprint = requestFingerprint(rec)
+	if ptrResolver != nil {
+		rec.Hostname = ptrResolver.Lookup(r.Context(), ip)
+	}
+	if r.TLS != nil {
+		rec.TLSVersion = tls.VersionName(r.TLS.Version)
+		rec.TLSCipher = tls.CipherSuiteName(r.TLS.CipherSuite)
+		// SNI from the ClientHello can disagree with the Host header;
+		// the mismatch itself is a useful vhost-scanning signal.
+		rec.SNI = sanitizeString(r.TLS.ServerName)
+		rec.JA3 = takeJA3(r.RemoteAddr)
+		// The listener requests (but never requires) a client cert, so
+		// anything an mTLS-probing client presents gets captured here.
+		if len(r.TLS.PeerCertificates) > 0 {
+			leaf := r.TLS.PeerCertificates[0]
+			rec.ClientCertSubj = sanitizeString(leaf.Subject.String())
+			rec.ClientCertIss = sanitizeString(leaf.Issuer.String())
+			sum := sha256.Sum256(leaf.Raw)
+			rec.ClientCertSHA = hex.EncodeToString(sum[:])
+		}
+	}
+	return rec
+}
+
+// rawRequest renders the request in wire form via DumpRequest,
+// truncated to -max-raw bytes. Control bytes are escaped the same way
+// as every other captured field, so the result stays a single
+// greppable line in the JSON log and the admin /recent view.
+func rawRequest(r *http.Request) string {
+	b, err := httputil.DumpRequest(r, true)
+	if err != nil {
+		return ""
+	}
+	if int64(len(b)) > *maxRaw {
+		b = b[:*maxRaw]
+	}
+	return sanitizeString(string(b))
+}
+
+// cookieMap flattens the request's cookies into name/value pairs;
+// replayed session cookies show up here instead of being buried in the
+// Cookie header.
+func cookieMap(r *http.Request) map[string]string {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(cookies))
+	for _, c := range cookies {
+		m[sanitizeString(c.Name)] = sanitizeString(c.Value)
+	}
+	return m
+}
+
+// localPort reports which listener a request arrived on, useful when
+// the process is serving several ports at once.
+func localPort(r *http.Request) string {
+	la, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(la.String())
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// formatEventTime renders t according to the -time-format flag: unix
+// epoch seconds, RFC3339 with milliseconds in UTC, or any custom Go
+// time layout.
+func formatEventTime(t time.Time) string {
+	switch *timeFormat {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "rfc3339":
+		return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	default:
+		return t.UTC().Format(*timeFormat)
+	}
+}
+
+// readBody reads at most *maxBody bytes of the request body and puts a
+// fresh reader back on r.Body so ParseForm and anything else downstream
+// still see the (possibly truncated) payload. It reads for every
+// method — attackers hide payloads behind GET and bespoke methods
+// precisely because naive loggers skip them — and the server has
+// already de-chunked Transfer-Encoding: chunked by this point. A read
+// error mid-body keeps whatever arrived before it.
+func readBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, *maxBody+1))
+	if err != nil && len(body) == 0 {
+		return nil, false
+	}
+	truncated := false
+	if int64(len(body)) > *maxBody {
+		body = body[:*maxBody]
+		truncated = true
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, truncated
+}
+
+// decodedPath returns the percent-decoded, cleaned form of the raw
+// request URI, so "/%2e%2e/%2e%2e/etc/passwd" is logged (and matched
+// by the classifiers) as "/etc/passwd". The raw RequestURI stays in
+// the record untouched for fidelity.
+func decodedPath(requestURI string) string {
+	p := requestURI
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		p = p[:i]
+	}
+	if p == "" {
+		return ""
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	return path.Clean(p)
+}
+
+// decodedBodyLimit is the hard cap on what a compressed body may expand
+// to. A few KB of crafted gzip can inflate to gigabytes; anything
+// hitting this limit is flagged as a bomb and stored truncated.
+const decodedBodyLimit = 10 << 20
+
+// decodeBody transparently decompresses a gzip or deflate request body
+// so the log stores readable content instead of compressed bytes. It
+// returns the decoded payload, whether a supported Content-Encoding was
+// seen, and whether decoding hit the bomb limit. Bodies that do not
+// decode (a lying header, or a payload cut off by the size cap) are
+// kept as-is.
+func decodeBody(body []byte, encoding string) (decoded []byte, compressed, bomb bool) {
+	if len(body) == 0 {
+		return body, false, false
+	}
+	var zr io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return body, true, false
+		}
+		zr = gz
+	case "deflate":
+		// RFC 9110 deflate is zlib-wrapped, but plenty of clients send
+		// raw deflate streams; try the spec's framing first.
+		z, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			zr = flate.NewReader(bytes.NewReader(body))
+		} else {
+			zr = z
+		}
+	default:
+		return body, false, false
+	}
+	decoded, err := io.ReadAll(io.LimitReader(zr, decodedBodyLimit+1))
+	if err != nil && len(decoded) == 0 {
+		return body, true, false
+	}
+	if int64(len(decoded)) > decodedBodyLimit {
+		return decoded[:decodedBodyLimit], true, true
+	}
+	return decoded, true, false
+}
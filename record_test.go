@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecordChunkedGETBody(t *testing.T) {
+	payload := `{"cmd":"id","path":"/etc"}`
+	r := httptest.NewRequest("GET", "/api/v1/exec", strings.NewReader(payload))
+	r.TransferEncoding = []string{"chunked"}
+	r.ContentLength = -1
+	r.Header.Set("Content-Type", "application/json")
+
+	rec := GenerateRecord(r)
+	if rec.Body != payload {
+		t.Errorf("Body = %q, want %q", rec.Body, payload)
+	}
+	if rec.BodyTruncated {
+		t.Error("small body marked truncated")
+	}
+	if rec.Smuggling {
+		t.Error("plain chunked request flagged as smuggling")
+	}
+}
+
+func TestGenerateRecordGzipBody(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"user":"admin"}`))
+	zw.Close()
+	r := httptest.NewRequest("POST", "/login", &buf)
+	r.Header.Set("Content-Encoding", "gzip")
+
+	rec := GenerateRecord(r)
+	if rec.Body != `{"user":"admin"}` {
+		t.Errorf("Body = %q, want decoded payload", rec.Body)
+	}
+	if !rec.BodyCompressed {
+		t.Error("gzip body not marked compressed")
+	}
+	if rec.BodyBomb {
+		t.Error("small body flagged as decompression bomb")
+	}
+}
+
+func TestDecodeBodyGarbageKeptRaw(t *testing.T) {
+	raw := []byte("not actually gzip")
+	decoded, compressed, bomb := decodeBody(raw, "gzip")
+	if !bytes.Equal(decoded, raw) {
+		t.Errorf("decoded = %q, want raw bytes back", decoded)
+	}
+	if !compressed || bomb {
+		t.Errorf("compressed = %v, bomb = %v", compressed, bomb)
+	}
+}
+
+func TestGenerateRecordDeleteWithBody(t *testing.T) {
+	r := httptest.NewRequest("DELETE", "/item/1", strings.NewReader("leftover=payload"))
+	rec := GenerateRecord(r)
+	if rec.Body != "leftover=payload" {
+		t.Errorf("Body = %q", rec.Body)
+	}
+}
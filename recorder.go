@@ -0,0 +1,8 @@
+package main
+
+// Recorder is a storage backend for captured requests. Implementations
+// must be safe for concurrent use; handleRequest may call Record from
+// many goroutines at once.
+type Recorder interface {
+	Record(r Record) error
+}
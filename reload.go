@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// runtimeConfig bundles the settings that may change while the server
+// is running. handleRequest reads the whole bundle through one atomic
+// load, so a SIGHUP reload swaps everything consistently and in-flight
+// requests keep the snapshot they started with.
+type runtimeConfig struct {
+	responseBody []byte
+	routes       []Route
+	extraHeaders http.Header
+	excluded     map[string]bool
+	pathSigs     []pathSignature
+	limiter      *RateLimiter
+}
+
+// cfg is the current runtime configuration.
+var cfg atomic.Pointer[runtimeConfig]
+
+// buildRuntimeConfig assembles a fresh bundle from the current flag
+// values and the files they point at. It is called at startup and
+// again on every SIGHUP.
+func buildRuntimeConfig() (*runtimeConfig, error) {
+	rc := &runtimeConfig{responseBody: []byte("hello\n")}
+	if *responseFile != "" {
+		body, err := os.ReadFile(*responseFile)
+		if err != nil {
+			return nil, err
+		}
+		rc.responseBody = body
+	}
+	if *routesFile != "" {
+		rts, err := LoadRoutes(*routesFile)
+		if err != nil {
+			return nil, err
+		}
+		rc.routes = rts
+	}
+	builtin, err := builtinRoutes()
+	if err != nil {
+		return nil, err
+	}
+	rc.routes = append(rc.routes, builtin...)
+	if *extraHdrsFile != "" {
+		hdrs, err := loadExtraHeaders(*extraHdrsFile)
+		if err != nil {
+			return nil, err
+		}
+		rc.extraHeaders = hdrs
+	}
+	if *pathSigsFile != "" {
+		sigs, err := LoadPathSignatures(*pathSigsFile)
+		if err != nil {
+			return nil, err
+		}
+		rc.pathSigs = sigs
+	}
+	if *excludePaths != "" {
+		rc.excluded = make(map[string]bool)
+		for _, p := range strings.Split(*excludePaths, ",") {
+			rc.excluded[strings.TrimSpace(p)] = true
+		}
+	}
+	if *rateLimit > 0 {
+		// Keep the running limiter when the rate has not changed, so a
+		// reload neither resets every client's token bucket nor leaks
+		// the replaced limiter's cleanup goroutine.
+		if prev := cfg.Load(); prev != nil && prev.limiter != nil && prev.limiter.rateIs(*rateLimit) {
+			rc.limiter = prev.limiter
+		} else {
+			rc.limiter = NewRateLimiter(*rateLimit)
+		}
+	}
+	return rc, nil
+}
+
+// reloadConfig handles SIGHUP: the config file (if any) is re-read and
+// the mutable settings are rebuilt and swapped in atomically. Any
+// failure keeps the running configuration untouched. Settings baked
+// into the listeners and recorders (addresses, ports, TLS, backend)
+// cannot change without a restart and are left alone.
+func reloadConfig() {
+	if *configFile != "" {
+		if err := loadConfig(*configFile); err != nil {
+			slog.Error("reload: failed to re-read config, keeping previous settings", "path", *configFile, "err", err)
+			return
+		}
+	}
+	rc, err := buildRuntimeConfig()
+	if err != nil {
+		slog.Error("reload failed, keeping previous settings", "err", err)
+		return
+	}
+	prev := cfg.Swap(rc)
+	if prev != nil && prev.limiter != nil && prev.limiter != rc.limiter {
+		prev.limiter.Stop()
+	}
+	slog.Info("configuration reloaded", "routes", len(rc.routes))
+	slog.Info("listener and backend settings are ignored until restart")
+}
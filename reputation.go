@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// reputationTTL prunes sources not seen for this long at save time, so
+// the state file and the in-memory map cannot grow forever.
+const reputationTTL = 30 * 24 * time.Hour
+
+// reputationMaxThreats caps the threat tags remembered per source.
+const reputationMaxThreats = 8
+
+// ipReputation is what the honeypot remembers about one source across
+// restarts.
+type ipReputation struct {
+	IP        string   `json:"ip"`
+	Count     int64    `json:"count"`
+	FirstSeen string   `json:"first_seen"`
+	LastSeen  string   `json:"last_seen"`
+	Threats   []string `json:"threats,omitempty"`
+	Malicious bool     `json:"malicious,omitempty"`
+
+	lastSeen time.Time
+}
+
+// reputationStore tracks per-IP reputation and serializes it on an
+// interval. Writes go to a temp file renamed into place, so a crash
+// mid-save leaves the previous state intact.
+type reputationStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*ipReputation
+	dirty   bool
+}
+
+// reputation is the optional store, nil unless -reputation-file is
+// set.
+var reputation *reputationStore
+
+// newReputationStore loads existing state from path (a missing file is
+// a fresh start) and saves every interval.
+func newReputationStore(path string, interval time.Duration) (*reputationStore, error) {
+	s := &reputationStore{path: path, entries: make(map[string]*ipReputation)}
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var loaded []*ipReputation
+		if err := json.Unmarshal(b, &loaded); err != nil {
+			return nil, err
+		}
+		for _, e := range loaded {
+			e.lastSeen, _ = time.Parse(time.RFC3339, e.LastSeen)
+			s.entries[e.IP] = e
+		}
+	}
+	go func() {
+		for range time.Tick(interval) {
+			if err := s.Save(); err != nil {
+				slog.Error("failed to save reputation state", "path", path, "err", err)
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Observe folds rec into its source's reputation and reports whether
+// that source was already marked malicious before this request.
+func (s *reputationStore) Observe(rec Record) bool {
+	if rec.ClientIP == "" {
+		return false
+	}
+	now := time.Now()
+	stamp := now.UTC().Format(time.RFC3339)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[rec.ClientIP]
+	if !ok {
+		e = &ipReputation{IP: rec.ClientIP, FirstSeen: stamp}
+		s.entries[rec.ClientIP] = e
+	}
+	knownBad := e.Malicious
+	e.Count++
+	e.LastSeen = stamp
+	e.lastSeen = now
+	for _, t := range rec.Threats {
+		if len(e.Threats) >= reputationMaxThreats {
+			break
+		}
+		if !slices.Contains(e.Threats, t) {
+			e.Threats = append(e.Threats, t)
+		}
+	}
+	if suspicious(rec) {
+		e.Malicious = true
+	}
+	s.dirty = true
+	return knownBad
+}
+
+// Save writes the current state atomically, pruning sources idle
+// beyond the TTL. It is a no-op when nothing changed. Entries are
+// copied while the lock is held so the marshal below never reads a
+// struct Observe is concurrently updating.
+func (s *reputationStore) Save() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	cutoff := time.Now().Add(-reputationTTL)
+	out := make([]ipReputation, 0, len(s.entries))
+	for ip, e := range s.entries {
+		if e.lastSeen.Before(cutoff) {
+			delete(s.entries, ip)
+			continue
+		}
+		c := *e
+		c.Threats = slices.Clone(e.Threats)
+		out = append(out, c)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// RetryRecorder retries failed writes with exponential backoff so a
+// transient error — a disk that is momentarily full, an interrupted
+// syscall — does not lose the record. Once the attempts are exhausted
+// the record is appended to an emergency spill file instead of being
+// dropped. It sits between the integrity wrapper and the raw backend,
+// so a retried write reuses the already-computed hash and the chain
+// stays aligned with the file.
+type RetryRecorder struct {
+	dst      Recorder
+	attempts int           // additional tries after the first failure
+	backoff  time.Duration // first retry delay, doubled each attempt
+
+	mu        sync.Mutex
+	spillPath string
+}
+
+// NewRetryRecorder wraps dst with attempts retries starting at backoff,
+// spilling to spillPath when they run out.
+func NewRetryRecorder(dst Recorder, attempts int, backoff time.Duration, spillPath string) *RetryRecorder {
+	return &RetryRecorder{dst: dst, attempts: attempts, backoff: backoff, spillPath: spillPath}
+}
+
+// Record writes r, retrying on failure. The async writer ahead of the
+// chain keeps the backoff sleeps off the request path.
+func (rr *RetryRecorder) Record(r Record) error {
+	err := rr.dst.Record(r)
+	delay := rr.backoff
+	for attempt := 0; err != nil && attempt < rr.attempts; attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = rr.dst.Record(r)
+	}
+	if err == nil {
+		return nil
+	}
+	if spillErr := rr.spill(r); spillErr != nil {
+		slog.Error("failed to spill record", "path", rr.spillPath, "err", spillErr)
+		return err
+	}
+	slog.Warn("record spilled after failed writes",
+		"attempts", rr.attempts+1, "spill_file", rr.spillPath, "err", err)
+	return nil
+}
+
+// spill appends r to the emergency file as one JSON line.
+func (rr *RetryRecorder) spill(r Record) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	f, err := os.OpenFile(rr.spillPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(r)
+}
+
+// Flush passes through to the destination.
+func (rr *RetryRecorder) Flush() error {
+	if f, ok := rr.dst.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the destination.
+func (rr *RetryRecorder) Close() error {
+	if c, ok := rr.dst.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Route maps a request path to a canned response so the honeypot can
+// mimic a realistic site layout (fake /admin login, robots.txt,
+// /wp-login.php, ...).
+type Route struct {
+	Name       string `json:"name"`
+	Pattern    string `json:"pattern"`      // exact path, or a prefix ending in *
+	File       string `json:"file"`         // file served as the response body
+	Status     int    `json:"status"`       // defaults to 200
+	MinDelayMs int    `json:"min_delay_ms"` // lower bound of artificial latency
+	MaxDelayMs int    `json:"max_delay_ms"` // upper bound; 0 disables the delay
+
+	body []byte
+}
+
+// LoadRoutes parses a JSON array of Route objects and reads each
+// response file up front so a missing file fails at startup, not per
+// request.
+func LoadRoutes(path string) ([]Route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rts []Route
+	if err := json.Unmarshal(data, &rts); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range rts {
+		rt := &rts[i]
+		if rt.Pattern == "" {
+			return nil, fmt.Errorf("route %q has no pattern", rt.Name)
+		}
+		if rt.Status == 0 {
+			rt.Status = http.StatusOK
+		}
+		if rt.MinDelayMs < 0 || rt.MaxDelayMs < rt.MinDelayMs {
+			return nil, fmt.Errorf("route %q: bad delay range %d..%d ms", rt.Name, rt.MinDelayMs, rt.MaxDelayMs)
+		}
+		if rt.File != "" {
+			rt.body, err = os.ReadFile(rt.File)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", rt.Name, err)
+			}
+		}
+	}
+	return rts, nil
+}
+
+// routeDelay picks a random latency within the route's configured
+// range, so bait paths respond like a loaded real server instead of
+// with a machine-flat timing profile. Zero means no delay.
+func routeDelay(rt *Route) time.Duration {
+	if rt == nil || rt.MaxDelayMs <= 0 {
+		return 0
+	}
+	ms := rt.MinDelayMs
+	if spread := rt.MaxDelayMs - rt.MinDelayMs; spread > 0 {
+		ms += rand.Intn(spread + 1)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// defaultRobots mimics a typical CMS install; disallowed paths double
+// as bait for crawlers that probe exactly what robots.txt hides.
+const defaultRobots = `User-agent: *
+Disallow: /wp-admin/
+Disallow: /admin/
+Disallow: /backup/
+Allow: /wp-admin/admin-ajax.php
+
+Sitemap: /sitemap.xml
+`
+
+// defaultFaviconB64 is a small valid 16x16 ICO so /favicon.ico answers
+// the way a real site would.
+const defaultFaviconB64 = "" +
+	"AAABAAEAEBAAAAEAIABIBAAAFgAAACgAAAAQAAAAIAAAAAEAIAAAAAAAIAQAAAAAAAAAAAAAAAAA" +
+	"AAAAAACIiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/" +
+	"iIiI/4iIiP+IiIj/iIiI/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d" +
+	"3d3/3d3d/93d3f/d3d3/iIiI/4iIiP/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d" +
+	"3f/d3d3/3d3d/93d3f/d3d3/3d3d/4iIiP+IiIj/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d" +
+	"/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f+IiIj/iIiI/93d3f/d3d3/3d3d/93d3f/d3d3/" +
+	"3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/iIiI/4iIiP/d3d3/3d3d/93d3f/d" +
+	"3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/4iIiP+IiIj/3d3d/93d" +
+	"3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f+IiIj/iIiI" +
+	"/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/" +
+	"iIiI/4iIiP/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d" +
+	"3d3/3d3d/4iIiP+IiIj/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d" +
+	"3f/d3d3/3d3d/93d3f+IiIj/iIiI/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d" +
+	"/93d3f/d3d3/3d3d/93d3f/d3d3/iIiI/4iIiP/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/" +
+	"3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/4iIiP+IiIj/3d3d/93d3f/d3d3/3d3d/93d3f/d" +
+	"3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f+IiIj/iIiI/93d3f/d3d3/3d3d/93d" +
+	"3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/iIiI/4iIiP/d3d3/3d3d" +
+	"/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/93d3f/d3d3/3d3d/4iIiP+IiIj/" +
+	"iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+IiIj/iIiI/4iIiP+I" +
+	"iIj/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+// builtinRoutes returns routes for /robots.txt and /favicon.ico,
+// honoring the -robots-file and -favicon-file overrides. They are
+// appended after the user's route table, so an explicit entry for
+// either path wins.
+func builtinRoutes() ([]Route, error) {
+	robots := []byte(defaultRobots)
+	if *robotsFile != "" {
+		b, err := os.ReadFile(*robotsFile)
+		if err != nil {
+			return nil, fmt.Errorf("robots file: %w", err)
+		}
+		robots = b
+	}
+	favicon, err := base64.StdEncoding.DecodeString(defaultFaviconB64)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt built-in favicon: %w", err)
+	}
+	if *faviconFile != "" {
+		b, err := os.ReadFile(*faviconFile)
+		if err != nil {
+			return nil, fmt.Errorf("favicon file: %w", err)
+		}
+		favicon = b
+	}
+	return []Route{
+		{Name: "robots", Pattern: "/robots.txt", Status: http.StatusOK, body: robots},
+		{Name: "favicon", Pattern: "/favicon.ico", Status: http.StatusOK, body: favicon},
+	}, nil
+}
+
+// matchRoute returns the first route in routes matching path, or nil.
+func matchRoute(routes []Route, path string) *Route {
+	for i := range routes {
+		rt := &routes[i]
+		if prefix, ok := strings.CutSuffix(rt.Pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return rt
+			}
+			continue
+		}
+		if path == rt.Pattern {
+			return rt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// isControl reports whether r is a control character we refuse to log
+// verbatim: everything below 0x20 except tab, plus DEL.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t') || r == 0x7f
+}
+
+// truncMarker is appended wherever a logged value was cut short.
+const truncMarker = "…(truncated)"
+
+// recordBytesBudget caps how many header bytes a single record may
+// carry in total, on top of the per-field limit, so a request with
+// hundreds of medium-sized headers cannot bloat one log row.
+const recordBytesBudget = 256 * 1024
+
+// truncateField caps s at -max-field-len bytes, cutting on a rune
+// boundary so a multi-byte UTF-8 sequence is never split, and appends
+// the truncation marker. 0 disables the limit.
+func truncateField(s string) string {
+	limit := int(*maxFieldLen)
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit] + truncMarker
+}
+
+// sanitizeString escapes control characters as \xNN so
+// attacker-supplied values cannot inject CRLF into the log files or
+// ANSI escape sequences into a terminal viewing them, and truncates
+// oversized values to -max-field-len. The escaped form keeps the
+// original bytes recoverable for analysis.
+func sanitizeString(s string) string {
+	if !strings.ContainsFunc(s, isControl) {
+		return truncateField(s)
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if isControl(r) {
+			fmt.Fprintf(&b, `\x%02x`, r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return truncateField(b.String())
+}
+
+// sanitizeValues returns a copy of v with every key and value passed
+// through sanitizeString.
+func sanitizeValues(v url.Values) url.Values {
+	if v == nil {
+		return nil
+	}
+	clean := make(url.Values, len(v))
+	for k, vals := range v {
+		cleanVals := make([]string, len(vals))
+		for i, val := range vals {
+			cleanVals[i] = sanitizeString(val)
+		}
+		clean[sanitizeString(k)] = cleanVals
+	}
+	return clean
+}
+
+// sanitizeHeader is sanitizeValues for http.Header, with an overall
+// byte budget: once a record's headers exceed recordBytesBudget, the
+// remaining values are replaced by the truncation marker.
+func sanitizeHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	clean := make(http.Header, len(h))
+	budget := recordBytesBudget
+	for k, vals := range h {
+		cleanVals := make([]string, len(vals))
+		for i, val := range vals {
+			if budget <= 0 {
+				cleanVals[i] = truncMarker
+				continue
+			}
+			cleanVals[i] = sanitizeString(val)
+			budget -= len(cleanVals[i])
+		}
+		clean[sanitizeString(k)] = cleanVals
+	}
+	return clean
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeStringCRLF(t *testing.T) {
+	got := sanitizeString("/admin\r\nX-Injected: 1")
+	want := `/admin\x0d\x0aX-Injected: 1`
+	if got != want {
+		t.Errorf("sanitizeString = %q, want %q", got, want)
+	}
+}
+
+func TestSanitizeStringANSIEscape(t *testing.T) {
+	got := sanitizeString("\x1b[2Jcurl/7.88")
+	want := `\x1b[2Jcurl/7.88`
+	if got != want {
+		t.Errorf("sanitizeString = %q, want %q", got, want)
+	}
+}
+
+func TestSanitizeStringKeepsTab(t *testing.T) {
+	in := "a\tb"
+	if got := sanitizeString(in); got != in {
+		t.Errorf("sanitizeString(%q) = %q, want unchanged", in, got)
+	}
+}
+
+func TestGenerateRecordSanitizesFields(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.URL.Path = "/a\r\nb"
+	r.Header.Set("User-Agent", "evil\x1b[31m")
+	rec := GenerateRecord(r)
+	if strings.ContainsAny(rec.Path, "\r\n") {
+		t.Errorf("Path still contains CR/LF: %q", rec.Path)
+	}
+	if strings.Contains(rec.UserAgent, "\x1b") {
+		t.Errorf("UserAgent still contains ESC: %q", rec.UserAgent)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart is the first inherited file descriptor under systemd
+// socket activation, per sd_listen_fds(3).
+const listenFdsStart = 3
+
+// activationListeners returns listeners for sockets inherited from
+// systemd socket activation, detected via the LISTEN_FDS/LISTEN_PID
+// environment. It returns nil when the process was not socket-activated
+// (or the variables were inherited from a parent and name a different
+// pid), in which case the caller binds its own ports as usual.
+func activationListeners() []net.Listener {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	// Consume the variables so they do not leak into child processes.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	var lns []net.Listener
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			slog.Error("inherited fd is not a listener", "fd", fd, "err", err)
+			continue
+		}
+		lns = append(lns, ln)
+	}
+	return lns
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pathSignature ties a path substring to the category of sensitive
+// resource it probes for.
+type pathSignature struct {
+	category string
+	pattern  string // lowercase substring of the request path
+}
+
+// defaultPathSignatures covers what scanners request most: leaked
+// secrets, database/backup artifacts, admin panels, traversal probes,
+// and exposed version-control metadata.
+var defaultPathSignatures = []pathSignature{
+	{"secrets", "/.env"},
+	{"secrets", "wp-config"},
+	{"secrets", "id_rsa"},
+	{"secrets", "/.aws/"},
+	{"secrets", "/.ssh/"},
+	{"traversal", "../"},
+	{"traversal", "..%2f"},
+	{"traversal", "/etc/passwd"},
+	{"backup", ".bak"},
+	{"backup", ".sql"},
+	{"backup", ".tar.gz"},
+	{"backup", "/backup"},
+	{"backup", "/dump"},
+	{"admin", "/admin"},
+	{"admin", "/wp-admin"},
+	{"admin", "/phpmyadmin"},
+	{"admin", "/manager/html"},
+	{"vcs", "/.git"},
+	{"vcs", "/.svn"},
+	{"vcs", "/.hg"},
+}
+
+// LoadPathSignatures parses a signature file with one
+// "category substring" pair per line; blank lines and #-comments are
+// skipped. Substrings are matched case-insensitively against the
+// request path.
+func LoadPathSignatures(path string) ([]pathSignature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sigs []pathSignature
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		category, pattern, ok := strings.Cut(text, " ")
+		if !ok || strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("%s:%d: want \"category substring\", got %q", path, line, text)
+		}
+		sigs = append(sigs, pathSignature{
+			category: category,
+			pattern:  strings.ToLower(strings.TrimSpace(pattern)),
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return sigs, nil
+}
+
+// classifySensitivePath returns the category of the first signature
+// found in p, or "" for an uninteresting path. The active set lives in
+// the runtime config so SIGHUP picks up signature-file edits; the
+// built-ins apply until the config is first loaded.
+func classifySensitivePath(p string) string {
+	sigs := defaultPathSignatures
+	if rc := cfg.Load(); rc != nil && rc.pathSigs != nil {
+		sigs = rc.pathSigs
+	}
+	lower := strings.ToLower(p)
+	for _, sig := range sigs {
+		if strings.Contains(lower, sig.pattern) {
+			return sig.category
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+)
+
+// sigdumpTopIPs is how many of the busiest sources the dump lists.
+const sigdumpTopIPs = 5
+
+// setupSignalDump makes SIGUSR1 flush buffered records and log a
+// runtime snapshot, for live debugging without stopping the server.
+func setupSignalDump() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	go func() {
+		for range ch {
+			if f, ok := recorder.(Flusher); ok {
+				if err := f.Flush(); err != nil {
+					slog.Error("failed to flush records", "err", err)
+				}
+			}
+			args := []any{
+				"goroutines", runtime.NumGoroutine(),
+				"requests_served", requestsServed.Load(),
+			}
+			if talkers != nil {
+				top := talkers.Snapshot()
+				if len(top) > sigdumpTopIPs {
+					top = top[:sigdumpTopIPs]
+				}
+				args = append(args, "top_ips", top)
+			}
+			slog.Info("stats dump", args...)
+		}
+	}()
+}
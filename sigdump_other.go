@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// setupSignalDump is a no-op where SIGUSR1 does not exist.
+func setupSignalDump() {}
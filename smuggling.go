@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// knownCodings are the Transfer-Encoding tokens a legitimate client
+// would send; anything else is treated as an obfuscation attempt.
+var knownCodings = map[string]bool{
+	"chunked":  true,
+	"identity": true,
+	"gzip":     true,
+	"deflate":  true,
+	"compress": true,
+}
+
+// detectSmuggling flags header combinations used to probe for HTTP
+// request smuggling: Content-Length alongside Transfer-Encoding,
+// repeated Content-Length values, or a Transfer-Encoding value that is
+// not a plain known coding (tricks like "xchunked" or embedded tabs
+// target lenient front-end parsers). Go's own parser rejects the worst
+// of these before they reach the handler, but the attempt itself is
+// exactly what a honeypot wants to see, so the raw header values are
+// inspected as far as the server surfaces them.
+func detectSmuggling(r *http.Request) bool {
+	cl := r.Header.Values("Content-Length")
+	te := r.Header.Values("Transfer-Encoding")
+	// The server moves a parsed Transfer-Encoding header into
+	// r.TransferEncoding; fold it back in so the check sees it.
+	te = append(te, r.TransferEncoding...)
+	if len(cl) > 0 && len(te) > 0 {
+		return true
+	}
+	if len(cl) > 1 {
+		return true
+	}
+	for _, value := range te {
+		for _, part := range strings.Split(value, ",") {
+			if strings.ContainsAny(part, "\t\v\f") {
+				return true
+			}
+			if !knownCodings[strings.ToLower(strings.TrimSpace(part))] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectSmugglingContentLengthWithTransferEncoding(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Content-Length", "4")
+	r.Header.Set("Transfer-Encoding", "chunked")
+	if !detectSmuggling(r) {
+		t.Error("CL together with TE should be flagged")
+	}
+}
+
+func TestDetectSmugglingDuplicateContentLength(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Add("Content-Length", "4")
+	r.Header.Add("Content-Length", "12")
+	if !detectSmuggling(r) {
+		t.Error("duplicate Content-Length should be flagged")
+	}
+}
+
+func TestDetectSmugglingObfuscatedTransferEncoding(t *testing.T) {
+	for _, value := range []string{"xchunked", "chunked\t", " chunked,zstd"} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Transfer-Encoding", value)
+		if !detectSmuggling(r) {
+			t.Errorf("Transfer-Encoding %q should be flagged", value)
+		}
+	}
+}
+
+func TestDetectSmugglingCleanRequests(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if detectSmuggling(r) {
+		t.Error("plain GET flagged")
+	}
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Transfer-Encoding", "chunked")
+	if detectSmuggling(r) {
+		t.Error("chunked-only POST flagged")
+	}
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Content-Length", "4")
+	if detectSmuggling(r) {
+		t.Error("Content-Length-only POST flagged")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS requests (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_time  INTEGER NOT NULL,
+	remote_addr TEXT NOT NULL,
+	client_ip   TEXT,
+	method      TEXT NOT NULL,
+	path        TEXT NOT NULL,
+	country     TEXT,
+	city        TEXT,
+	headers     TEXT,
+	post_form   TEXT,
+	body        TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_requests_remote_addr ON requests(remote_addr);
+CREATE INDEX IF NOT EXISTS idx_requests_method      ON requests(method);
+CREATE INDEX IF NOT EXISTS idx_requests_event_time  ON requests(event_time);
+`
+
+// SQLiteRecorder writes each record as a row in an indexed requests
+// table. Headers and PostForm are stored as JSON text columns so the
+// structured data survives round-tripping.
+type SQLiteRecorder struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	insert *sql.Stmt
+}
+
+// NewSQLiteRecorder opens (creating if needed) the database at path and
+// ensures the schema exists.
+func NewSQLiteRecorder(path string) (*SQLiteRecorder, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite handles one writer at a time; a single connection plus the
+	// recorder mutex keeps inserts serialized without SQLITE_BUSY noise.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	insert, err := db.Prepare(`INSERT INTO requests
+		(event_time, remote_addr, client_ip, method, path, country, city, headers, post_form, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteRecorder{db: db, insert: insert}, nil
+}
+
+// Record inserts r as a new row.
+func (s *SQLiteRecorder) Record(r Record) error {
+	headers, err := json.Marshal(r.Headers)
+	if err != nil {
+		return err
+	}
+	form, err := json.Marshal(r.PostForm)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.insert.Exec(r.Timestamp, r.RemoteAddr, r.ClientIP, r.Method,
+		r.Path, r.Country, r.City, string(headers), string(form), r.Body)
+	return err
+}
+
+// sqliteDB is the raw SQLite backend when -backend=sqlite, reachable by
+// the admin /search endpoint underneath whatever recorder wrappers are
+// stacked on top. Nil for every other backend.
+var sqliteDB *SQLiteRecorder
+
+// searchMaxLimit caps how many rows one /search query may return.
+const searchMaxLimit = 1000
+
+// searchRow is one /search result, mirroring the queryable columns of
+// the requests table.
+type searchRow struct {
+	ID         int64           `json:"id"`
+	EventTime  int64           `json:"event_time"`
+	RemoteAddr string          `json:"remote_addr"`
+	ClientIP   string          `json:"client_ip,omitempty"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Country    string          `json:"country,omitempty"`
+	City       string          `json:"city,omitempty"`
+	HeadersRaw json.RawMessage `json:"headers,omitempty"`
+	PostForm   json.RawMessage `json:"post_form,omitempty"`
+	Body       string          `json:"body,omitempty"`
+}
+
+// Search returns the newest rows matching the given filters; empty
+// filters are skipped, a zero since means no time bound. The WHERE
+// clause is assembled from fixed fragments with placeholder arguments,
+// so filter values never reach the SQL text.
+func (s *SQLiteRecorder) Search(ip, method string, since int64, limit int) ([]searchRow, error) {
+	if limit <= 0 || limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+	var where []string
+	var args []any
+	if ip != "" {
+		where = append(where, "client_ip = ?")
+		args = append(args, ip)
+	}
+	if method != "" {
+		where = append(where, "method = ?")
+		args = append(args, strings.ToUpper(method))
+	}
+	if since > 0 {
+		where = append(where, "event_time >= ?")
+		args = append(args, since)
+	}
+	query := `SELECT id, event_time, remote_addr, client_ip, method, path,
+		country, city, headers, post_form, body FROM requests`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY event_time DESC LIMIT ?"
+	args = append(args, limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []searchRow{}
+	for rows.Next() {
+		var r searchRow
+		var headers, form sql.NullString
+		if err := rows.Scan(&r.ID, &r.EventTime, &r.RemoteAddr, &r.ClientIP,
+			&r.Method, &r.Path, &r.Country, &r.City, &headers, &form, &r.Body); err != nil {
+			return nil, err
+		}
+		if headers.Valid && headers.String != "" {
+			r.HeadersRaw = json.RawMessage(headers.String)
+		}
+		if form.Valid && form.String != "" {
+			r.PostForm = json.RawMessage(form.String)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// Close releases the prepared statement and the database handle.
+func (s *SQLiteRecorder) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insert.Close()
+	return s.db.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+)
+
+// ipCounterCap bounds the top-talkers map; a spoofed-source flood
+// evicts the least recently seen IPs instead of exhausting memory.
+const ipCounterCap = 10000
+
+// IPCounter tracks request counts per client IP for the admin /stats
+// view, bounded by LRU eviction.
+type IPCounter struct {
+	mu      sync.Mutex
+	cap     int
+	entries map[string]*list.Element
+	order   *list.List // front = most recently seen
+}
+
+// ipCount is one /stats row.
+type ipCount struct {
+	IP    string `json:"ip"`
+	Count int    `json:"count"`
+}
+
+// NewIPCounter returns a counter holding at most cap distinct IPs.
+func NewIPCounter(cap int) *IPCounter {
+	return &IPCounter{
+		cap:     cap,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Inc bumps the count for ip, evicting the least recently seen IP if
+// the map is full.
+func (c *IPCounter) Inc(ip string) {
+	if ip == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[ip]; ok {
+		el.Value.(*ipCount).Count++
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.cap {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ipCount).IP)
+	}
+	c.entries[ip] = c.order.PushFront(&ipCount{IP: ip, Count: 1})
+}
+
+// Snapshot returns all counts sorted descending, busiest IP first.
+func (c *IPCounter) Snapshot() []ipCount {
+	c.mu.Lock()
+	out := make([]ipCount, 0, len(c.entries))
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		out = append(out, *el.Value.(*ipCount))
+	}
+	c.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+// Reset drops every counter.
+func (c *IPCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order = list.New()
+}
+
+// talkers is the per-IP request counter, nil unless -admin-addr is
+// configured.
+var talkers *IPCounter
+
+// fingerprints counts requests per structural fingerprint for the
+// admin /fingerprints view, nil unless -admin-addr is configured. The
+// LRU bound works the same as for IPs: a flood of unique shapes evicts
+// the stalest entries.
+var fingerprints *IPCounter
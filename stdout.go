@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// redactedMark replaces masked values in the stdout echo.
+const redactedMark = "[redacted]"
+
+// StdoutRecorder writes each record as a JSON line to standard output
+// so container log collectors (Docker, Fluentd, ...) can pick it up.
+// Fields named by -redact are masked first: credentials belong in the
+// access-controlled log file, not in every monitoring pipeline that
+// tails the container.
+type StdoutRecorder struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	redact []string
+}
+
+// NewStdoutRecorder returns a recorder emitting to os.Stdout with the
+// -redact list applied.
+func NewStdoutRecorder() *StdoutRecorder {
+	var fields []string
+	for _, f := range strings.Split(*redactFields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return &StdoutRecorder{enc: json.NewEncoder(os.Stdout), redact: fields}
+}
+
+// Record prints r as one JSON line with sensitive fields masked.
+func (s *StdoutRecorder) Record(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(redactRecord(r, s.redact))
+}
+
+// redactRecord masks the named fields on a copy of r. The entries
+// auth_user, auth_pass, body, and cookies address those record fields;
+// anything else is treated as a header name whose values are masked.
+// Maps are copied before masking so the original record, which is also
+// on its way to the real log, is never touched.
+func redactRecord(r Record, fields []string) Record {
+	for _, f := range fields {
+		switch strings.ToLower(f) {
+		case "auth_user":
+			if r.AuthUser != "" {
+				r.AuthUser = redactedMark
+			}
+		case "auth_pass":
+			if r.AuthPass != "" {
+				r.AuthPass = redactedMark
+			}
+		case "body":
+			if r.Body != "" {
+				r.Body = redactedMark
+			}
+		case "cookies":
+			if len(r.Cookies) > 0 {
+				masked := make(map[string]string, len(r.Cookies))
+				for name := range r.Cookies {
+					masked[name] = redactedMark
+				}
+				r.Cookies = masked
+			}
+		default:
+			name := http.CanonicalHeaderKey(f)
+			if len(r.Headers[name]) == 0 {
+				continue
+			}
+			masked := make(http.Header, len(r.Headers))
+			for k, v := range r.Headers {
+				masked[k] = v
+			}
+			masked[name] = []string{redactedMark}
+			r.Headers = masked
+		}
+	}
+	return r
+}
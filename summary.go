@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// summaryTopN is how many URIs and user agents each report lists.
+const summaryTopN = 10
+
+// summaryMaxKeys caps each counter map so a randomized scan cannot
+// grow them without bound between reports; overflow lands in the
+// totals but not in the top-N tables.
+const summaryMaxKeys = 10000
+
+// summaryCollector accumulates cheap in-memory counters and appends a
+// human-readable report to a file on an interval, giving an at-a-glance
+// view of an interval's traffic without analysis queries against the
+// log files.
+type summaryCollector struct {
+	mu      sync.Mutex
+	start   time.Time
+	total   int64
+	ips     map[string]struct{}
+	uris    map[string]int
+	agents  map[string]int
+	threats map[string]int
+}
+
+// summary is the optional report collector, nil unless -summary-file
+// is set.
+var summary *summaryCollector
+
+// newSummaryCollector starts a collector appending a report to path
+// every interval.
+func newSummaryCollector(path string, interval time.Duration) *summaryCollector {
+	s := &summaryCollector{}
+	s.reset(time.Now())
+	go func() {
+		for now := range time.Tick(interval) {
+			if err := s.report(path, now); err != nil {
+				slog.Error("failed to write summary", "path", path, "err", err)
+			}
+		}
+	}()
+	return s
+}
+
+// reset clears the counters for a fresh interval. The caller must hold
+// s.mu, except in the constructor.
+func (s *summaryCollector) reset(now time.Time) {
+	s.start = now
+	s.total = 0
+	s.ips = make(map[string]struct{})
+	s.uris = make(map[string]int)
+	s.agents = make(map[string]int)
+	s.threats = make(map[string]int)
+}
+
+// Observe folds one request into the current interval's counters. It
+// only touches maps under a briefly-held mutex, so request handling is
+// never blocked on report generation.
+func (s *summaryCollector) Observe(rec Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	if rec.ClientIP != "" && len(s.ips) < summaryMaxKeys {
+		s.ips[rec.ClientIP] = struct{}{}
+	}
+	if _, ok := s.uris[rec.Path]; ok || len(s.uris) < summaryMaxKeys {
+		s.uris[rec.Path]++
+	}
+	if _, ok := s.agents[rec.UserAgent]; ok || len(s.agents) < summaryMaxKeys {
+		s.agents[rec.UserAgent]++
+	}
+	for _, tag := range rec.Threats {
+		s.threats[tag]++
+	}
+}
+
+// report renders the finished interval and appends it to path. The
+// counters are swapped out under the lock; formatting and the write
+// happen outside it.
+func (s *summaryCollector) report(path string, now time.Time) error {
+	s.mu.Lock()
+	start, total, ips := s.start, s.total, len(s.ips)
+	uris, agents, threats := s.uris, s.agents, s.threats
+	s.reset(now)
+	s.mu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "=== %s — %s ===\n",
+		start.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "requests: %d  unique ips: %d\n", total, ips)
+	writeTop(&sb, "top uris", uris)
+	writeTop(&sb, "top user agents", agents)
+	if len(threats) > 0 {
+		sb.WriteString("threat tags:")
+		for _, kc := range sortedCounts(threats) {
+			fmt.Fprintf(&sb, " %s=%d", kc.key, kc.count)
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(sb.String())
+	return err
+}
+
+// keyCount pairs a counter key with its count for sorting.
+type keyCount struct {
+	key   string
+	count int
+}
+
+// sortedCounts returns m's entries busiest first, ties alphabetical.
+func sortedCounts(m map[string]int) []keyCount {
+	out := make([]keyCount, 0, len(m))
+	for k, c := range m {
+		out = append(out, keyCount{k, c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+// writeTop renders the summaryTopN busiest entries of m under a
+// heading.
+func writeTop(sb *strings.Builder, heading string, m map[string]int) {
+	if len(m) == 0 {
+		return
+	}
+	sb.WriteString(heading + ":\n")
+	for i, kc := range sortedCounts(m) {
+		if i >= summaryTopN {
+			break
+		}
+		fmt.Fprintf(sb, "  %6d  %s\n", kc.count, kc.key)
+	}
+}
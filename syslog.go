@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// syslogPriority is facility local0, severity informational.
+const syslogPriority = 134
+
+// SyslogRecorder ships each record to a remote collector as an RFC
+// 5424 message with the interesting fields as structured data. A lost
+// connection is re-dialed on the next record rather than taking the
+// server down.
+type SyslogRecorder struct {
+	mu       sync.Mutex
+	network  string
+	addr     string
+	conn     net.Conn
+	hostname string
+}
+
+// NewSyslogRecorder dials endpoint, which must look like
+// udp://10.0.0.1:514 or tcp://collector:6514.
+func NewSyslogRecorder(endpoint string) (*SyslogRecorder, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q (want udp or tcp)", u.Scheme)
+	}
+	hostname, _ := os.Hostname()
+	s := &SyslogRecorder{network: u.Scheme, addr: u.Host, hostname: hostname}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the collector. The caller must hold s.mu except in the
+// constructor.
+func (s *SyslogRecorder) connect() error {
+	conn, err := net.Dial(s.network, s.addr)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// sdEscape escapes the characters RFC 5424 forbids inside structured
+// data param values.
+func sdEscape(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return r.Replace(v)
+}
+
+// format renders r as a single RFC 5424 message.
+func (s *SyslogRecorder) format(r Record) string {
+	var sd strings.Builder
+	sd.WriteString("[honeypot@32473")
+	for _, kv := range [][2]string{
+		{"id", r.ID},
+		{"remote_addr", r.RemoteAddr},
+		{"client_ip", r.ClientIP},
+		{"local_port", r.LocalPort},
+		{"method", r.Method},
+		{"path", r.Path},
+		{"host", r.Host},
+		{"user_agent", r.UserAgent},
+		{"classification", r.Classification},
+		{"country", r.Country},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&sd, " %s=%q", kv[0], sdEscape(kv[1]))
+	}
+	sd.WriteString("]")
+	return fmt.Sprintf("<%d>1 %s %s honeypot %d %s %s -\n",
+		syslogPriority, r.EventTime, s.hostname, os.Getpid(), r.ID, sd.String())
+}
+
+// Record sends r to the collector, re-dialing once if the connection
+// has gone away.
+func (s *SyslogRecorder) Record(r Record) error {
+	msg := []byte(s.format(r))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		if err := s.connect(); err != nil {
+			return err
+		}
+		_, err = s.conn.Write(msg)
+		return err
+	}
+	return nil
+}
+
+// Close tears down the collector connection.
+func (s *SyslogRecorder) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is non-nil only when -otel-endpoint is configured, so the
+// untraced path costs a single nil check.
+var tracer trace.Tracer
+
+// tracerShutdown flushes buffered spans during shutdown; nil when
+// tracing is disabled.
+var tracerShutdown func(context.Context) error
+
+// setupTracing wires an OTLP/HTTP exporter pointed at endpoint and
+// installs a batching tracer provider tagged with the honeypot name.
+func setupTracing(endpoint string) error {
+	exp, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpoint(endpoint),
+		otlptracehttp.WithInsecure())
+	if err != nil {
+		return err
+	}
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceName("honeypot"),
+			attribute.String("honeypot.name", honeypotName)))
+	if err != nil {
+		return err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	tracer = tp.Tracer("honeypot")
+	tracerShutdown = tp.Shutdown
+	return nil
+}
+
+// annotateSpan copies the interesting record fields onto the active
+// span and stores the trace ID on the record for correlation.
+func annotateSpan(ctx context.Context, rec *Record) {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("http.request.method", rec.Method),
+		attribute.String("url.path", rec.Path),
+		attribute.String("client.address", rec.ClientIP),
+		attribute.String("honeypot.classification", rec.Classification))
+	rec.TraceID = span.SpanContext().TraceID().String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// upstreamProxy forwards requests to the real backend in mirror mode,
+// nil unless -upstream is set. The honeypot then answers with the real
+// service's responses — indistinguishable from it — while still
+// recording everything that passes through.
+var upstreamProxy *httputil.ReverseProxy
+
+// setupUpstream builds the reverse proxy for raw. Proxy errors mark the
+// response writer as failed instead of writing anything, so
+// handleRequest can fall back to the canned response.
+func setupUpstream(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	p := httputil.NewSingleHostReverseProxy(u)
+	p.Transport = &http.Transport{ResponseHeaderTimeout: 10 * time.Second}
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if uw, ok := w.(*upstreamWriter); ok {
+			uw.failed = true
+		}
+		slog.Warn("upstream request failed", "upstream", raw, "err", err)
+	}
+	upstreamProxy = p
+	return nil
+}
+
+// upstreamWriter captures the status and size of a proxied response.
+// Once the proxy reports failure it swallows any further writes, so the
+// fallback path still has a clean response to write to.
+type upstreamWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	failed bool
+}
+
+func (u *upstreamWriter) WriteHeader(code int) {
+	if u.failed {
+		return
+	}
+	u.status = code
+	u.ResponseWriter.WriteHeader(code)
+}
+
+func (u *upstreamWriter) Write(p []byte) (int, error) {
+	if u.failed {
+		return len(p), nil
+	}
+	if u.status == 0 {
+		u.status = http.StatusOK
+	}
+	n, err := u.ResponseWriter.Write(p)
+	u.bytes += int64(n)
+	return n, err
+}
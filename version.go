@@ -0,0 +1,14 @@
+package main
+
+// Build metadata, injected at link time:
+//
+//	go build -ldflags "-X main.version=v1.2.3 \
+//	    -X main.commit=$(git rev-parse --short HEAD) \
+//	    -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// The defaults identify ad-hoc `go run` / `go build` binaries.
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
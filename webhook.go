@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	webhookTimeout = 5 * time.Second
+	webhookRetries = 3
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookFormatter renders an alert payload for one chat platform.
+// Adding a platform means adding a type here and a case to
+// newWebhookFormatter.
+type webhookFormatter interface {
+	Format(rec Record) ([]byte, error)
+}
+
+// webhookFmt is the formatter chosen by -webhook-format, set in main.
+var webhookFmt webhookFormatter = jsonFormatter{}
+
+// newWebhookFormatter maps the -webhook-format flag to a formatter.
+func newWebhookFormatter(format string) (webhookFormatter, error) {
+	switch format {
+	case "json":
+		return jsonFormatter{}, nil
+	case "slack":
+		return slackFormatter{}, nil
+	case "discord":
+		return discordFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+}
+
+// alertSummary is the one-line human description shared by the chat
+// formatters.
+func alertSummary(rec Record) string {
+	why := rec.Classification
+	if len(rec.Threats) > 0 {
+		why = strings.Join(rec.Threats, ", ")
+	}
+	if why == "" {
+		why = "suspicious request"
+	}
+	return fmt.Sprintf("%s %s from %s (%s) at %s", rec.Method, rec.Path, rec.ClientIP, why, rec.EventTime)
+}
+
+// jsonFormatter posts the raw record, the historical default.
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(rec Record) ([]byte, error) {
+	return json.Marshal(rec)
+}
+
+// slackFormatter emits a Slack Block Kit payload.
+type slackFormatter struct{}
+
+func (slackFormatter) Format(rec Record) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"blocks": []any{map[string]any{
+			"type": "section",
+			"text": map[string]any{
+				"type": "mrkdwn",
+				"text": ":rotating_light: *Honeypot alert*\n" + alertSummary(rec),
+			},
+		}},
+	})
+}
+
+// discordFormatter emits a Discord embed.
+type discordFormatter struct{}
+
+func (discordFormatter) Format(rec Record) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"embeds": []any{map[string]any{
+			"title":       "Honeypot alert",
+			"description": alertSummary(rec),
+			"color":       0xcc0000,
+			"timestamp":   time.Unix(rec.Timestamp, 0).UTC().Format(time.RFC3339),
+		}},
+	})
+}
+
+// suspicious reports whether rec looks like an attack worth alerting
+// on: a tagged scanner User-Agent, any detected threat payload, or
+// smuggling-probe headers.
+func suspicious(rec Record) bool {
+	return rec.Classification != "" || len(rec.Threats) > 0 || rec.Smuggling
+}
+
+// webhookBudget bounds one record's delivery, retries and backoff
+// included, so webhook goroutines can never pile up indefinitely.
+const webhookBudget = 30 * time.Second
+
+// sendWebhook POSTs rec as JSON to url with a bounded retry. It is
+// meant to run in its own goroutine so delivery never delays the
+// response to the attacker; ctx cancellation aborts both the in-flight
+// request and any backoff sleep.
+func sendWebhook(ctx context.Context, url string, rec Record) {
+	b, err := webhookFmt.Format(rec)
+	if err != nil {
+		slog.Error("failed to format webhook payload", "id", rec.ID, "err", err)
+		return
+	}
+	for attempt := 1; attempt <= webhookRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			slog.Error("failed to build webhook request", "url", url, "err", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := webhookClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		slog.Warn("webhook delivery failed", "attempt", attempt, "err", err)
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+			slog.Error("webhook delivery cancelled", "url", url, "id", rec.ID, "err", ctx.Err())
+			return
+		}
+	}
+	slog.Error("webhook delivery gave up", "url", url, "id", rec.ID)
+}